@@ -1,14 +1,117 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"pvz_server/internal/app/apiserver"
+	"pvz_server/internal/app/grpcserver"
+	"pvz_server/internal/app/metrics"
+	"pvz_server/internal/app/outbox"
+	"pvz_server/internal/app/webhook"
+	"sync"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	srv := apiserver.NewServer()
+	deps := apiserver.NewDependencies()
+
+	if err := apiserver.BootstrapAdmin(context.Background(), deps.Store); err != nil {
+		log.Fatalf("failed to create the admin: %v", err)
+	}
+
+	srv := apiserver.NewServerWithDeps(deps)
+	grpcSrv := grpcserver.NewServer(deps.Store, grpcserver.Auth{
+		Keys:        deps.Keys,
+		Revocations: deps.Store,
+		Policy:      deps.Policy,
+	})
+	metricsSrv := metrics.NewServer()
+
+	// Events always reach webhook subscriptions, OUTBOX_PUBLISHER adds
+	// another destination.
+	publishers := outbox.Fanout{webhook.NewDispatcher(deps.Store)}
+
+	publisher, err := outbox.PublisherFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure outbox publisher: %v", err)
+	}
+
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9000"
+	}
+
+	errCh := make(chan error, 3)
+
+	go func() {
+		errCh <- srv.Run(":8080")
+	}()
+
+	go func() {
+		errCh <- grpcSrv.Run(grpcAddr)
+	}()
+
+	go func() {
+		errCh <- metricsSrv.Run(metricsAddr)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+
+	var workers sync.WaitGroup
+
+	workers.Add(2)
+
+	go func() {
+		defer workers.Done()
+		outbox.NewRelay(deps.Store, publishers).Run(workersCtx)
+	}()
+
+	go func() {
+		defer workers.Done()
+		webhook.NewWorker(deps.Store, nil).Run(workersCtx)
+	}()
+
+	var runErr error
+
+	select {
+	case runErr = <-errCh:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down HTTP server: %v", err)
+	}
+
+	if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down metrics server: %v", err)
+	}
+
+	grpcSrv.Stop()
+
+	stopWorkers()
+	workers.Wait()
 
-	if err := srv.Run(":8080"); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	if runErr != nil {
+		log.Fatalf("failed to start server: %v", runErr)
 	}
 }
@@ -0,0 +1,127 @@
+// Package apierror turns errors into the JSON error body shared by every
+// HTTP endpoint:
+//
+//	{"code": "pvz_not_found", "message": "pvz not found", "requestId": "...", "details": [...]}
+//
+// Codes are stable and meant for machines, messages are for humans and may
+// change.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"pvz_server/internal/app/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key holding the current request ID.
+const RequestIDKey = "requestID"
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Response struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestId,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+var (
+	ErrInvalidRequest     = New(http.StatusBadRequest, "invalid_request", "invalid request")
+	ErrInvalidPVZID       = New(http.StatusBadRequest, "invalid_pvz_id", "invalid pvz ID")
+	ErrInvalidCredentials = New(http.StatusUnauthorized, "invalid_credentials", "invalid credentials")
+	ErrUnauthorized       = New(http.StatusUnauthorized, "unauthorized", "unauthorized")
+	ErrInvalidUserID      = New(http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+	ErrAccessDenied       = New(http.StatusForbidden, "access_denied", "access denied")
+	ErrPVZNotAssigned     = New(http.StatusForbidden, "pvz_not_assigned", "employee is not assigned to this pvz")
+	ErrInternal           = New(http.StatusInternalServerError, "internal_error", "internal server error")
+)
+
+// storeErrors maps store sentinel errors to API errors.
+var storeErrors = []struct {
+	err    error
+	apiErr *Error
+}{
+	{store.ErrPVZNotFound, New(http.StatusNotFound, "pvz_not_found", "pvz not found")},
+	{store.ErrCityNotFound, New(http.StatusNotFound, "city_not_found", "city not found")},
+	{store.ErrProductTypeNotFound, New(http.StatusNotFound, "product_type_not_found", "product type not found")},
+	{store.ErrProductNotFound, New(http.StatusNotFound, "product_not_found", "product not found")},
+	{store.ErrUserNotFound, New(http.StatusNotFound, "user_not_found", "user not found")},
+	{store.ErrAssignmentNotFound, New(http.StatusNotFound, "assignment_not_found", "assignment not found")},
+	{store.ErrWebhookNotFound, New(http.StatusNotFound, "webhook_not_found", "webhook not found")},
+	{store.ErrDeliveryNotFound, New(http.StatusNotFound, "delivery_not_found", "delivery not found")},
+	{store.ErrDeliveryNotDead, New(http.StatusConflict, "delivery_not_dead", "only dead deliveries can be replayed")},
+	{store.ErrReceptionAlreadyExists, New(http.StatusConflict, "reception_in_progress", "previous reception is not closed")},
+	{store.ErrNoActiveReception, New(http.StatusConflict, "no_active_reception", "no active reception")},
+	{store.ErrNoProductsToDelete, New(http.StatusConflict, "no_products_to_delete", "no products to delete")},
+	{store.ErrProductReceptionClosed, New(http.StatusConflict, "product_reception_closed", "the reception of the product is already closed")},
+	{store.ErrProductAlreadyExists, New(http.StatusConflict, "product_already_exists", "product already exists")},
+	{store.ErrBarcodeAlreadyExists, New(http.StatusConflict, "barcode_already_exists", "a product with this barcode is already in the reception")},
+	{store.ErrIdempotencyKeyInUse, New(http.StatusConflict, "idempotency_key_in_use", "a request with this idempotency key is still in progress")},
+	{store.ErrCityAlreadyExists, New(http.StatusConflict, "city_already_exists", "city name is already taken")},
+	{store.ErrProductTypeAlreadyExists, New(http.StatusConflict, "product_type_already_exists", "product type already exists")},
+	{store.ErrUserAlreadyExists, New(http.StatusConflict, "user_already_exists", "user already exists")},
+	{store.ErrCityNotAllowed, New(http.StatusUnprocessableEntity, "city_not_allowed", "unsupported city")},
+	{store.ErrProductTypeNotAllowed, New(http.StatusUnprocessableEntity, "product_type_not_allowed", "unsupported product type")},
+	{store.ErrRoleNotAllowed, New(http.StatusUnprocessableEntity, "role_not_allowed", "unsupported role")},
+	{store.ErrUserNotEmployee, New(http.StatusUnprocessableEntity, "user_not_employee", "only employees can be assigned to a pvz")},
+	{store.ErrIdempotencyKeyMismatch, New(http.StatusUnprocessableEntity, "idempotency_key_mismatch", "idempotency key was used with a different request")},
+	{store.ErrEventTypeNotAllowed, New(http.StatusUnprocessableEntity, "event_type_not_allowed", "unsupported event type")},
+	{store.ErrRefreshTokenInvalid, New(http.StatusUnauthorized, "invalid_refresh_token", "invalid refresh token")},
+	{store.ErrRefreshTokenReused, New(http.StatusUnauthorized, "refresh_token_reused", "refresh token reused, all sessions of this login are revoked")},
+	{store.ErrInvalidProductID, New(http.StatusBadRequest, "invalid_product_id", "invalid product ID")},
+	{store.ErrInvalidCursor, New(http.StatusBadRequest, "invalid_cursor", "invalid cursor")},
+	{store.ErrDatabase, New(http.StatusServiceUnavailable, "database_unavailable", "database is unavailable, try again later")},
+}
+
+// From resolves err to an API error. Errors the API does not know about
+// become ErrInternal.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, m := range storeErrors {
+		if errors.Is(err, m.err) {
+			return m.apiErr
+		}
+	}
+
+	return ErrInternal
+}
+
+// Respond writes err as the error body and aborts the request.
+func Respond(c *gin.Context, err error) {
+	apiErr := From(err)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		_ = c.Error(err)
+	}
+
+	c.AbortWithStatusJSON(apiErr.Status, Response{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		RequestID: c.GetString(RequestIDKey),
+		Details:   apiErr.Details,
+	})
+}
@@ -0,0 +1,108 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/store"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrom_StoreErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{store.ErrPVZNotFound, http.StatusNotFound, "pvz_not_found"},
+		{store.ErrReceptionAlreadyExists, http.StatusConflict, "reception_in_progress"},
+		{store.ErrNoActiveReception, http.StatusConflict, "no_active_reception"},
+		{store.ErrCityNotAllowed, http.StatusUnprocessableEntity, "city_not_allowed"},
+		{store.ErrDatabase, http.StatusServiceUnavailable, "database_unavailable"},
+		{fmt.Errorf("close reception: %w", store.ErrNoActiveReception), http.StatusConflict, "no_active_reception"},
+		{errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			apiErr := apierror.From(tt.err)
+
+			assert.Equal(t, tt.status, apiErr.Status)
+			assert.Equal(t, tt.code, apiErr.Code)
+		})
+	}
+}
+
+func TestRespond_HidesInternalErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		apierror.Respond(c, errors.New("pq: connection refused"))
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+	assert.Contains(t, w.Body.String(), `"code":"internal_error"`)
+}
+
+type bindInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=employee moderator"`
+}
+
+func TestRespondBinding_ListsInvalidFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var in bindInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			apierror.RespondBinding(c, err)
+		}
+	})
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"email":"nope","role":"admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_request", resp.Code)
+	assert.ElementsMatch(t, []apierror.FieldError{
+		{Field: "email", Message: "must be a valid email"},
+		{Field: "role", Message: "must be one of: employee moderator"},
+	}, resp.Details)
+}
+
+func TestRespondBinding_MalformedJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var in bindInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			apierror.RespondBinding(c, err)
+		}
+	})
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), "details")
+}
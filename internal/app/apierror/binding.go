@@ -0,0 +1,85 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report JSON field names in details instead of Go struct field names.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// RespondBinding reports a request body or query that failed to bind,
+// listing every invalid field in details when the validator knows them.
+func RespondBinding(c *gin.Context, err error) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		Respond(c, ErrInvalidRequest)
+		return
+	}
+
+	apiErr := *ErrInvalidRequest
+	apiErr.Details = make([]FieldError, 0, len(verrs))
+
+	for _, fe := range verrs {
+		apiErr.Details = append(apiErr.Details, FieldError{
+			Field:   fieldPath(fe),
+			Message: fieldMessage(fe),
+		})
+	}
+
+	Respond(c, &apiErr)
+}
+
+// fieldPath names the field from the request root, e.g. "items[2].type"
+// for an element of a list.
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "min":
+		switch fe.Kind() {
+		case reflect.Slice:
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		case reflect.Int, reflect.Int64:
+			return fmt.Sprintf("must be at least %s", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters long", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "uuid":
+		return "must be a valid UUID"
+	default:
+		return "is invalid"
+	}
+}
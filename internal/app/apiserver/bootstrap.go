@@ -0,0 +1,40 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/utils"
+	"strings"
+)
+
+// BootstrapAdmin creates the admin from ADMIN_EMAIL and ADMIN_PASSWORD, so a
+// new installation has someone to create other privileged users through
+// POST /users. An existing user with that email is left as is.
+func BootstrapAdmin(ctx context.Context, users store.UserCreator) error {
+	email := strings.ToLower(strings.TrimSpace(os.Getenv("ADMIN_EMAIL")))
+	password := os.Getenv("ADMIN_PASSWORD")
+
+	if email == "" && password == "" {
+		return nil
+	}
+
+	if email == "" || len(password) < 8 {
+		return fmt.Errorf("ADMIN_EMAIL and an ADMIN_PASSWORD of at least 8 characters must be set together")
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.CreateUser(ctx, email, hash, model.Admin)
+	if errors.Is(err, store.ErrUserAlreadyExists) {
+		return nil
+	}
+
+	return err
+}
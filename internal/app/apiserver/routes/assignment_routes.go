@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerAssignmentRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	protected := r.Group("/users/:userId/pvz")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	protected.GET("", require(deps, authz.AssignmentRead), handlers.ListAssignedPVZs(deps.Store))
+	protected.POST("", require(deps, authz.AssignmentManage), handlers.AssignPVZ(deps.Store))
+	protected.DELETE("/:pvzId", require(deps, authz.AssignmentManage), handlers.UnassignPVZ(deps.Store))
+}
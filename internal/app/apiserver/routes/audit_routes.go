@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerAuditRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	protected := r.Group("/")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	protected.GET("/audit", require(deps, authz.AuditRead), handlers.GetAuditEvents(deps.Store))
+}
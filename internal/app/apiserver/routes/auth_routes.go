@@ -1,11 +1,31 @@
 package routes
 
 import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
 	"pvz_server/internal/handlers"
 
 	"github.com/gin-gonic/gin"
 )
 
-func registerAuthRoutes(r *gin.Engine) {
-	r.POST("/dummyLogin", handlers.DummyLogin)
+func registerAuthRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	r.POST("/register", handlers.Register(deps.Store))
+	r.POST("/login", handlers.Login(deps.Store, deps.Keys))
+	r.POST("/auth/refresh", handlers.Refresh(deps.Store, deps.Keys))
+	r.GET("/.well-known/jwks.json", handlers.JWKS(deps.Keys))
+
+	protected := r.Group("/auth")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	protected.POST("/logout", handlers.Logout(deps.Store))
+
+	users := r.Group("/users")
+	users.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	users.POST("", require(deps, authz.UserCreate), handlers.CreateUser(deps.Store))
+
+	if deps.DevMode {
+		r.POST("/dummyLogin", handlers.DummyLogin(deps.Keys))
+	}
 }
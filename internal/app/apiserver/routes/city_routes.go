@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerCityRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	protected := r.Group("/cities")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	protected.GET("", require(deps, authz.PVZRead), handlers.ListCities(deps.Store))
+	protected.POST("", require(deps, authz.CityManage), handlers.AddCity(deps.Store))
+	protected.POST("/:cityId/rename", require(deps, authz.CityManage), handlers.RenameCity(deps.Store))
+	protected.POST("/:cityId/deactivate", require(deps, authz.CityManage), handlers.SetCityActive(deps.Store, false))
+	protected.POST("/:cityId/activate", require(deps, authz.CityManage), handlers.SetCityActive(deps.Store, true))
+}
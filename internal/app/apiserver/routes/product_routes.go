@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
 	"pvz_server/internal/app/middleware"
 	"pvz_server/internal/handlers"
@@ -10,7 +11,10 @@ import (
 
 func registerProductRoutes(r *gin.Engine, d *deps.Dependencies) {
 	protected := r.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(d.Keys, d.Store))
 
-	protected.POST("/products", handlers.AddProduct(d.Store))
+	protected.GET("/products", require(d, authz.PVZRead), handlers.FindProducts(d.Store))
+	protected.POST("/products", require(d, authz.ProductAdd), idempotent(d), handlers.AddProduct(d.Store, d.Store))
+	protected.POST("/products/batch", require(d, authz.ProductAdd), idempotent(d), handlers.AddProducts(d.Store, d.Store))
+	protected.DELETE("/products/:productId", require(d, authz.ProductDelete), handlers.DeleteProduct(d.Store, d.Store))
 }
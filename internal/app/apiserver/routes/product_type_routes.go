@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerProductTypeRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	protected := r.Group("/product-types")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
+
+	protected.GET("", require(deps, authz.PVZRead), handlers.ListProductTypes(deps.Store))
+	protected.POST("", require(deps, authz.ProductTypeManage), handlers.AddProductType(deps.Store))
+	protected.PUT("/:code", require(deps, authz.ProductTypeManage), handlers.UpdateProductType(deps.Store))
+	protected.POST("/:code/deactivate", require(deps, authz.ProductTypeManage), handlers.SetProductTypeActive(deps.Store, false))
+	protected.POST("/:code/activate", require(deps, authz.ProductTypeManage), handlers.SetProductTypeActive(deps.Store, true))
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
 	"pvz_server/internal/app/middleware"
 	"pvz_server/internal/handlers"
@@ -8,11 +9,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func registerPVZRoutes(r *gin.Engine, deps *deps.Dependencies) {
+func registerPVZRoutes(r *gin.Engine, deps *deps.Dependencies, shutdown <-chan struct{}) {
 	protected := r.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
 
-	protected.POST("/pvz", handlers.CreatePVZ(deps.Store))
-	protected.POST("/pvz/:pvzId/delete_last_product", handlers.DeleteLastProduct(deps.Store))
-	protected.GET("/pvz", handlers.GetPVZList(deps.Store))
+	protected.POST("/pvz", require(deps, authz.PVZCreate), idempotent(deps), handlers.CreatePVZ(deps.Store))
+	protected.POST("/pvz/:pvzId/delete_last_product", require(deps, authz.ProductDelete), idempotent(deps), handlers.DeleteLastProduct(deps.Store, deps.Store))
+	protected.GET("/pvz", require(deps, authz.PVZRead), handlers.GetPVZList(deps.Store))
+	protected.GET("/pvz/:pvzId/events", require(deps, authz.PVZRead), handlers.StreamPVZEvents(deps.Store, handlers.DefaultEventPollInterval, shutdown))
 }
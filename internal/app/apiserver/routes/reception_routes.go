@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
 	"pvz_server/internal/app/middleware"
 	"pvz_server/internal/handlers"
@@ -10,8 +11,8 @@ import (
 
 func registerReceptionRoutes(r *gin.Engine, deps *deps.Dependencies) {
 	protected := r.Group(("/"))
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store))
 
-	protected.POST("/receptions", handlers.CreateReception(deps.Store))
-	protected.POST("/pvz/:pvzId/close_last_reception", handlers.CloseLastReception(deps.Store))
+	protected.POST("/receptions", require(deps, authz.ReceptionCreate), idempotent(deps), handlers.CreateReception(deps.Store, deps.Store))
+	protected.POST("/pvz/:pvzId/close_last_reception", require(deps, authz.ReceptionClose), idempotent(deps), handlers.CloseLastReception(deps.Store, deps.Store))
 }
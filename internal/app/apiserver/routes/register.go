@@ -1,14 +1,35 @@
 package routes
 
 import (
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, deps *deps.Dependencies) {
-	registerAuthRoutes(r)
-	registerPVZRoutes(r, deps)
+// RegisterRoutes registers every route. shutdown is closed when the server
+// starts shutting down and ends long-lived event streams.
+func RegisterRoutes(r *gin.Engine, deps *deps.Dependencies, shutdown <-chan struct{}) {
+	registerAuthRoutes(r, deps)
+	registerPVZRoutes(r, deps, shutdown)
 	registerReceptionRoutes(r, deps)
 	registerProductRoutes(r, deps)
+	registerAssignmentRoutes(r, deps)
+	registerAuditRoutes(r, deps)
+	registerWebhookRoutes(r, deps)
+	registerCityRoutes(r, deps)
+	registerProductTypeRoutes(r, deps)
+}
+
+// require enforces perm under the configured policy. Routes using it must be
+// registered behind AuthMiddleware.
+func require(d *deps.Dependencies, perm authz.Permission) gin.HandlerFunc {
+	return middleware.RequirePermission(d.Policy, perm)
+}
+
+// idempotent lets clients retry the route safely with an Idempotency-Key.
+// Routes using it must be registered behind AuthMiddleware.
+func idempotent(d *deps.Dependencies) gin.HandlerFunc {
+	return middleware.Idempotency(d.Store, middleware.DefaultIdempotencyTTL)
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerWebhookRoutes(r *gin.Engine, deps *deps.Dependencies) {
+	protected := r.Group("/webhooks")
+	protected.Use(middleware.AuthMiddleware(deps.Keys, deps.Store), require(deps, authz.WebhookManage))
+
+	protected.POST("", handlers.CreateWebhook(deps.Store))
+	protected.GET("", handlers.ListWebhooks(deps.Store))
+	protected.DELETE("/:webhookId", handlers.DeleteWebhook(deps.Store))
+	protected.GET("/:webhookId/deliveries", handlers.ListWebhookDeliveries(deps.Store))
+	protected.POST("/:webhookId/deliveries/:deliveryId/replay", handlers.ReplayWebhookDelivery(deps.Store))
+}
@@ -1,43 +1,101 @@
 package apiserver
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"pvz_server/internal/app/apiserver/routes"
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/middleware"
 	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/memory"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
 type Server struct {
-	engine *gin.Engine
+	engine     *gin.Engine
+	httpServer *http.Server
 }
 
 func NewServer() *Server {
-	db, err := connectDB()
+	return NewServerWithDeps(NewDependencies())
+}
+
+// NewDependencies builds the dependencies shared by the HTTP and gRPC servers.
+// STORE_BACKEND selects the store: "postgres" (default) or "memory". The
+// server refuses to start without a JWT key, see jwtkeys.FromEnv.
+// AUTHZ_POLICY_FILE replaces the built-in role policy.
+func NewDependencies() *deps.Dependencies {
+	keys, err := jwtkeys.FromEnv()
 
 	if err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+		log.Fatalf("Failed to load JWT keys: %v", err)
 	}
 
-	s := &Server{
-		engine: gin.Default(),
+	return &deps.Dependencies{
+		Store:   newStore(os.Getenv("STORE_BACKEND")),
+		Keys:    keys,
+		Policy:  newPolicy(os.Getenv("AUTHZ_POLICY_FILE")),
+		DevMode: os.Getenv("DEV_MODE") == "true",
 	}
+}
 
-	deps := &deps.Dependencies{
-		Store: store.New(db),
+func newPolicy(path string) *authz.Policy {
+	if path == "" {
+		return authz.DefaultPolicy()
 	}
 
-	routes.RegisterRoutes(s.engine, deps)
-	return s
+	policy, err := authz.LoadPolicy(path)
+
+	if err != nil {
+		log.Fatalf("Failed to load authorization policy: %v", err)
+	}
+
+	return policy
+}
+
+func newStore(backend string) store.Repository {
+	switch backend {
+	case "", "postgres":
+		db, err := connectDB()
+
+		if err != nil {
+			log.Fatalf("Failed to connect to the database: %v", err)
+		}
+
+		return store.New(db)
+	case "memory":
+		log.Println("Using in-memory store, data will be lost on restart")
+		return memory.New()
+	default:
+		log.Fatalf("Unknown STORE_BACKEND %q", backend)
+		return nil
+	}
 }
 
 func (s *Server) Run(addr string) error {
-	return s.engine.Run(addr)
+	s.httpServer.Addr = addr
+
+	err := s.httpServer.ListenAndServe()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
 }
 
 func connectDB() (*sql.DB, error) {
@@ -58,6 +116,18 @@ func NewServerWithDeps(deps *deps.Dependencies) *Server {
 	s := &Server{
 		engine: gin.Default(),
 	}
-	routes.RegisterRoutes(s.engine, deps)
+	s.httpServer = &http.Server{Handler: s.engine}
+
+	// Shutdown waits for active requests, event streams would hold it up
+	// until the timeout unless they end when it starts.
+	shutdown := make(chan struct{})
+	var once sync.Once
+	s.httpServer.RegisterOnShutdown(func() {
+		once.Do(func() { close(shutdown) })
+	})
+
+	s.engine.Use(middleware.RequestID(), middleware.MetricsMiddleware())
+
+	routes.RegisterRoutes(s.engine, deps, shutdown)
 	return s
 }
@@ -0,0 +1,182 @@
+// Package authz maps roles to the permissions they grant. Routes declare the
+// permission they require and the policy decides which roles hold it, so
+// adding a role or moving a capability is a policy change, not a code change.
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"pvz_server/internal/app/model"
+	"sort"
+)
+
+type Permission string
+
+const (
+	PVZCreate         Permission = "pvz:create"
+	PVZRead           Permission = "pvz:read"
+	ReceptionCreate   Permission = "reception:create"
+	ReceptionClose    Permission = "reception:close"
+	ProductAdd        Permission = "product:add"
+	ProductDelete     Permission = "product:delete"
+	AssignmentRead    Permission = "assignment:read"
+	AssignmentManage  Permission = "assignment:manage"
+	AuditRead         Permission = "audit:read"
+	WebhookManage     Permission = "webhook:manage"
+	CityManage        Permission = "city:manage"
+	ProductTypeManage Permission = "product_type:manage"
+	UserCreate        Permission = "user:create"
+)
+
+// All lists every permission known to the server.
+var All = []Permission{
+	PVZCreate,
+	PVZRead,
+	ReceptionCreate,
+	ReceptionClose,
+	ProductAdd,
+	ProductDelete,
+	AssignmentRead,
+	AssignmentManage,
+	AuditRead,
+	WebhookManage,
+	CityManage,
+	ProductTypeManage,
+	UserCreate,
+}
+
+// wildcard in a policy file grants every permission.
+const wildcard = "*"
+
+type Policy struct {
+	roles map[model.UserRole]map[Permission]bool
+}
+
+// Allows reports whether role holds perm. Unknown roles hold nothing.
+func (p *Policy) Allows(role model.UserRole, perm Permission) bool {
+	return p.roles[role][perm]
+}
+
+// Permissions returns the sorted permissions of role.
+func (p *Policy) Permissions(role model.UserRole) []Permission {
+	perms := make([]Permission, 0, len(p.roles[role]))
+
+	for perm := range p.roles[role] {
+		perms = append(perms, perm)
+	}
+
+	sort.Slice(perms, func(i, j int) bool {
+		return perms[i] < perms[j]
+	})
+
+	return perms
+}
+
+// DefaultPolicy is used when no policy file is configured. Reception and
+// product permissions are additionally limited to the PVZs an employee is
+// assigned to. Only employees can be assigned, so no other role is granted
+// them: the admin holds everything else.
+func DefaultPolicy() *Policy {
+	p, err := NewPolicy(map[model.UserRole][]string{
+		model.Employee: {
+			string(PVZRead),
+			string(ReceptionCreate),
+			string(ReceptionClose),
+			string(ProductAdd),
+			string(ProductDelete),
+		},
+		model.Moderator: {
+			string(PVZCreate),
+			string(PVZRead),
+			string(AssignmentRead),
+			string(AssignmentManage),
+			string(AuditRead),
+			string(WebhookManage),
+			string(CityManage),
+			string(ProductTypeManage),
+		},
+		model.Admin: {
+			string(PVZCreate),
+			string(PVZRead),
+			string(AssignmentRead),
+			string(AssignmentManage),
+			string(AuditRead),
+			string(WebhookManage),
+			string(CityManage),
+			string(ProductTypeManage),
+			string(UserCreate),
+		},
+		model.Auditor: {
+			string(PVZRead),
+			string(AssignmentRead),
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// NewPolicy builds a policy from role names to permission names. Unknown
+// permissions are rejected so a typo in a policy file fails at startup
+// instead of silently denying access.
+func NewPolicy(roles map[model.UserRole][]string) (*Policy, error) {
+	known := make(map[Permission]bool, len(All))
+	for _, perm := range All {
+		known[perm] = true
+	}
+
+	p := &Policy{roles: make(map[model.UserRole]map[Permission]bool, len(roles))}
+
+	for role, names := range roles {
+		perms := make(map[Permission]bool, len(names))
+
+		for _, name := range names {
+			if name == wildcard {
+				for _, perm := range All {
+					perms[perm] = true
+				}
+				continue
+			}
+
+			if !known[Permission(name)] {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, name)
+			}
+
+			perms[Permission(name)] = true
+		}
+
+		p.roles[role] = perms
+	}
+
+	return p, nil
+}
+
+type policyFile struct {
+	Roles map[model.UserRole][]string `json:"roles"`
+}
+
+// LoadPolicy reads a JSON policy file:
+//
+//	{"roles": {"employee": ["pvz:read", "reception:create"], "admin": ["*"]}}
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f policyFile
+
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if len(f.Roles) == 0 {
+		return nil, fmt.Errorf("%s: no roles defined", path)
+	}
+
+	return NewPolicy(f.Roles)
+}
@@ -0,0 +1,78 @@
+package authz_test
+
+import (
+	"os"
+	"path/filepath"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := authz.DefaultPolicy()
+
+	assert.True(t, p.Allows(model.Employee, authz.ReceptionCreate))
+	assert.False(t, p.Allows(model.Employee, authz.PVZCreate))
+	assert.True(t, p.Allows(model.Moderator, authz.PVZCreate))
+	assert.False(t, p.Allows(model.Moderator, authz.ProductAdd))
+	assert.True(t, p.Allows(model.Auditor, authz.PVZRead))
+	assert.False(t, p.Allows(model.Auditor, authz.AssignmentManage))
+	assert.True(t, p.Allows(model.Admin, authz.UserCreate))
+	assert.False(t, p.Allows("guest", authz.PVZRead))
+}
+
+// Assignment-scoped permissions are useless to roles that cannot be
+// assigned; granting them would only turn into pvz_not_assigned errors.
+func TestDefaultPolicy_AssignmentScopedOnlyForEmployees(t *testing.T) {
+	p := authz.DefaultPolicy()
+	scoped := []authz.Permission{authz.ReceptionCreate, authz.ReceptionClose, authz.ProductAdd, authz.ProductDelete}
+
+	for role := range model.AllowedUserRoles {
+		for _, perm := range scoped {
+			assert.Equal(t, role == model.Employee, p.Allows(role, perm), "%s %s", role, perm)
+		}
+	}
+}
+
+func TestDefaultPolicy_MatchesShippedFile(t *testing.T) {
+	p, err := authz.LoadPolicy(filepath.Join("..", "..", "..", "configs", "authz_policy.json"))
+	require.NoError(t, err)
+
+	def := authz.DefaultPolicy()
+
+	for role := range model.AllowedUserRoles {
+		assert.Equal(t, def.Permissions(role), p.Permissions(role), role)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"roles": {"auditor": ["pvz:read", "reception:close"]}}`), 0o600))
+
+	p, err := authz.LoadPolicy(path)
+	require.NoError(t, err)
+
+	assert.True(t, p.Allows(model.Auditor, authz.ReceptionClose))
+	assert.False(t, p.Allows(model.Employee, authz.PVZRead))
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"UnknownPermission": `{"roles": {"employee": ["pvz:destroy"]}}`,
+		"NoRoles":           `{"roles": {}}`,
+		"Malformed":         `{"roles": `,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := authz.LoadPolicy(path)
+			assert.Error(t, err)
+		})
+	}
+}
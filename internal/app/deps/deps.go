@@ -1,7 +1,14 @@
 package deps
 
-import "pvz_server/internal/app/store"
+import (
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/store"
+)
 
 type Dependencies struct {
-	Store *store.Store
+	Store   store.Repository
+	Keys    *jwtkeys.Manager
+	Policy  *authz.Policy
+	DevMode bool
 }
@@ -0,0 +1,60 @@
+package grpcserver
+
+import (
+	"context"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/utils"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Auth holds what the server needs to check callers, the same as the HTTP
+// AuthMiddleware and RequirePermission.
+type Auth struct {
+	Keys        *jwtkeys.Manager
+	Revocations store.AccessTokenChecker
+	Policy      *authz.Policy
+}
+
+// authInterceptor accepts calls carrying a valid, unrevoked access token in
+// the "authorization" metadata whose role holds perm. Every method of
+// PVZService only reads, so a single permission covers them all.
+func authInterceptor(auth Auth, perm authz.Permission) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+
+		if len(values) != 1 || !strings.HasPrefix(values[0], "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		token, err := utils.ParseAccessToken(auth.Keys, strings.TrimPrefix(values[0], "Bearer "))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		revoked, err := auth.Revocations.IsAccessTokenRevoked(ctx, token.JTI)
+		if err != nil {
+			return nil, storeError(err, "failed to check token")
+		}
+
+		if revoked {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if !auth.Policy.Allows(model.UserRole(token.Role), perm) {
+			return nil, status.Error(codes.PermissionDenied, "access denied")
+		}
+
+		ctx = store.WithActor(ctx, model.Actor{UserID: token.UserID, Role: token.Role})
+
+		return handler(ctx, req)
+	}
+}
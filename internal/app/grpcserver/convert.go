@@ -0,0 +1,50 @@
+package grpcserver
+
+import (
+	"pvz_server/internal/app/model"
+	pvzv1 "pvz_server/internal/pb/pvz/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoPVZWithReceptions(p *model.PVZWithReceptions) *pvzv1.PVZWithReceptions {
+	out := &pvzv1.PVZWithReceptions{
+		Pvz: &pvzv1.PVZ{
+			Id:               p.PVZ.ID,
+			RegistrationDate: timestamppb.New(p.PVZ.RegistrationDate),
+			City:             string(p.PVZ.City),
+		},
+		Receptions: make([]*pvzv1.ReceptionWithProducts, 0, len(p.Receptions)),
+	}
+
+	for _, r := range p.Receptions {
+		out.Receptions = append(out.Receptions, toProtoReceptionWithProducts(r))
+	}
+
+	return out
+}
+
+func toProtoReceptionWithProducts(r model.ReceptionWithProducts) *pvzv1.ReceptionWithProducts {
+	out := &pvzv1.ReceptionWithProducts{
+		Reception: &pvzv1.Reception{
+			Id:       r.Reception.ID,
+			DateTime: timestamppb.New(r.Reception.DateTime),
+			PvzId:    r.Reception.PvzID,
+			Status:   string(r.Reception.Status),
+		},
+		Products: make([]*pvzv1.Product, 0, len(r.Products)),
+	}
+
+	for _, pr := range r.Products {
+		out.Products = append(out.Products, &pvzv1.Product{
+			Id:          pr.ID,
+			DateTime:    timestamppb.New(pr.DateTime),
+			Type:        string(pr.Type),
+			ReceptionId: pr.ReceptionID,
+			Barcode:     pr.Barcode,
+			OrderId:     pr.OrderID,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,162 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	pvzv1 "pvz_server/internal/pb/pvz/v1"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type PVZStore interface {
+	store.PVZFetcher
+	store.PVZGetter
+}
+
+type Server struct {
+	pvzv1.UnimplementedPVZServiceServer
+
+	store  PVZStore
+	server *grpc.Server
+}
+
+func NewServer(storeInst PVZStore, auth Auth) *Server {
+	s := &Server{
+		store:  storeInst,
+		server: grpc.NewServer(grpc.UnaryInterceptor(authInterceptor(auth, authz.PVZRead))),
+	}
+
+	pvzv1.RegisterPVZServiceServer(s.server, s)
+	return s
+}
+
+func (s *Server) Run(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(lis)
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	err := s.server.Serve(lis)
+
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+func (s *Server) GetPVZList(ctx context.Context, req *pvzv1.GetPVZListRequest) (*pvzv1.GetPVZListResponse, error) {
+	var startDate, endDate *time.Time
+
+	if req.GetStartDate() != nil {
+		t := req.GetStartDate().AsTime()
+		startDate = &t
+	}
+
+	if req.GetEndDate() != nil {
+		t := req.GetEndDate().AsTime()
+		endDate = &t
+	}
+
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 5
+	}
+
+	if page < 1 || limit < 1 || limit > 30 {
+		return nil, status.Error(codes.InvalidArgument, "invalid pagination")
+	}
+
+	var (
+		pvzs []*model.PVZWithReceptions
+		next *store.PVZCursor
+		err  error
+	)
+
+	if req.Cursor != nil {
+		if req.GetPage() != 0 {
+			return nil, status.Error(codes.InvalidArgument, "page cannot be combined with cursor")
+		}
+
+		var after *store.PVZCursor
+
+		if req.GetCursor() != "" {
+			after, err = store.DecodePVZCursor(req.GetCursor())
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+			}
+		}
+
+		pvzs, next, err = s.store.FetchPVZListAfter(ctx, startDate, endDate, after, limit)
+	} else {
+		pvzs, err = s.store.FetchPVZList(ctx, startDate, endDate, page, limit)
+	}
+
+	if err != nil {
+		return nil, storeError(err, "failed to fetch PVZ list")
+	}
+
+	resp := &pvzv1.GetPVZListResponse{
+		Pvzs: make([]*pvzv1.PVZWithReceptions, 0, len(pvzs)),
+	}
+
+	if next != nil {
+		resp.NextCursor = next.Encode()
+	}
+
+	for _, pvz := range pvzs {
+		resp.Pvzs = append(resp.Pvzs, toProtoPVZWithReceptions(pvz))
+	}
+
+	return resp, nil
+}
+
+func (s *Server) GetPVZ(ctx context.Context, req *pvzv1.GetPVZRequest) (*pvzv1.GetPVZResponse, error) {
+	if err := uuid.Validate(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid pvz ID")
+	}
+
+	pvz, err := s.store.FetchPVZ(ctx, req.GetId())
+
+	switch {
+	case errors.Is(err, store.ErrPVZNotFound):
+		return nil, status.Error(codes.NotFound, "pvz not found")
+	case err != nil:
+		return nil, storeError(err, "failed to fetch PVZ")
+	}
+
+	return &pvzv1.GetPVZResponse{Pvz: toProtoPVZWithReceptions(pvz)}, nil
+}
+
+// storeError turns an unexpected store error into a status. A database
+// outage is Unavailable, like 503 in the HTTP API, so clients retry it;
+// anything else is Internal with msg.
+func storeError(err error, msg string) error {
+	if errors.Is(err, store.ErrDatabase) {
+		return status.Error(codes.Unavailable, "database is unavailable, try again later")
+	}
+
+	return status.Error(codes.Internal, msg)
+}
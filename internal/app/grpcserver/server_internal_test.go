@@ -0,0 +1,306 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/grpcserver"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	pvzv1 "pvz_server/internal/pb/pvz/v1"
+	"pvz_server/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockPVZStore struct {
+	fetchListFunc  func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error)
+	fetchAfterFunc func(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error)
+	fetchFunc      func(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error)
+}
+
+func (m *mockPVZStore) FetchPVZList(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
+	return m.fetchListFunc(ctx, start, end, page, limit)
+}
+
+func (m *mockPVZStore) FetchPVZListAfter(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+	return m.fetchAfterFunc(ctx, start, end, after, limit)
+}
+
+func (m *mockPVZStore) FetchPVZ(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+	return m.fetchFunc(ctx, pvzID)
+}
+
+type mockRevocations struct {
+	revoked map[string]bool
+}
+
+func (m *mockRevocations) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return m.revoked[jti], nil
+}
+
+var testKeys = newTestKeys()
+
+func newTestKeys() *jwtkeys.Manager {
+	key, _ := jwtkeys.NewHMACKey("test", []byte("test-secret"))
+	keys, _ := jwtkeys.NewManager("test", key)
+	return keys
+}
+
+func setupClient(t *testing.T, mock *mockPVZStore) pvzv1.PVZServiceClient {
+	token, err := utils.GenerateJWT(testKeys, utils.NewAccessToken("u-1", "employee"))
+	require.NoError(t, err)
+
+	return newClient(t, mock, &mockRevocations{}, token)
+}
+
+// newClient connects to a server backed by mock and sends token as a bearer
+// token with every call, or no token when it is empty.
+func newClient(t *testing.T, mock *mockPVZStore, revocations *mockRevocations, token string) pvzv1.PVZServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpcserver.NewServer(mock, grpcserver.Auth{
+		Keys:        testKeys,
+		Revocations: revocations,
+		Policy:      authz.DefaultPolicy(),
+	})
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	withToken := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withToken),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pvzv1.NewPVZServiceClient(conn)
+}
+
+func TestAuth_Rejected(t *testing.T) {
+	mock := &mockPVZStore{
+		fetchListFunc: func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
+			t.Error("store must not be called")
+			return nil, nil
+		},
+	}
+
+	revokedToken := utils.NewAccessToken("u-1", "employee")
+	revocations := &mockRevocations{revoked: map[string]bool{revokedToken.JTI: true}}
+
+	sign := func(access utils.AccessToken) string {
+		token, err := utils.GenerateJWT(testKeys, access)
+		require.NoError(t, err)
+		return token
+	}
+
+	expired := utils.NewAccessToken("u-1", "employee")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		token string
+		code  codes.Code
+	}{
+		{"no token", "", codes.Unauthenticated},
+		{"malformed token", "not-a-jwt", codes.Unauthenticated},
+		{"expired token", sign(expired), codes.Unauthenticated},
+		{"revoked token", sign(revokedToken), codes.Unauthenticated},
+		{"role without pvz:read", sign(utils.NewAccessToken("u-1", "client")), codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, mock, revocations, tt.token)
+
+			_, err := client.GetPVZList(context.Background(), &pvzv1.GetPVZListRequest{})
+
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestAuth_Accepted(t *testing.T) {
+	var actor model.Actor
+	mock := &mockPVZStore{
+		fetchFunc: func(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+			actor = store.ActorFromContext(ctx)
+			return &model.PVZWithReceptions{PVZ: model.PVZ{ID: pvzID}}, nil
+		},
+	}
+
+	token, err := utils.GenerateJWT(testKeys, utils.NewAccessToken("u-1", "auditor"))
+	require.NoError(t, err)
+	client := newClient(t, mock, &mockRevocations{}, token)
+
+	_, err = client.GetPVZ(context.Background(), &pvzv1.GetPVZRequest{Id: "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11"})
+
+	require.NoError(t, err)
+	assert.Equal(t, model.Actor{UserID: "u-1", Role: "auditor"}, actor)
+}
+
+func TestGetPVZList_Success(t *testing.T) {
+	now := time.Date(2025, 4, 13, 10, 0, 0, 0, time.UTC)
+
+	var gotPage, gotLimit int
+	mock := &mockPVZStore{
+		fetchListFunc: func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
+			gotPage, gotLimit = page, limit
+			return []*model.PVZWithReceptions{
+				{
+					PVZ: model.PVZ{ID: "pvz-1", RegistrationDate: now, City: model.Kazan},
+					Receptions: []model.ReceptionWithProducts{
+						{
+							Reception: model.Reception{ID: "r-1", DateTime: now, PvzID: "pvz-1", Status: model.InProgress},
+							Products: []model.Product{
+								{ID: "p-1", DateTime: now, Type: model.Shoes, ReceptionID: "r-1", Barcode: "4600000000017", OrderID: "order-7"},
+								{ID: "p-2", DateTime: now, Type: model.Electronics, ReceptionID: "r-1"},
+							},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	client := setupClient(t, mock)
+
+	resp, err := client.GetPVZList(context.Background(), &pvzv1.GetPVZListRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, gotPage)
+	assert.Equal(t, 5, gotLimit)
+	require.Len(t, resp.GetPvzs(), 1)
+
+	pvz := resp.GetPvzs()[0]
+	assert.Equal(t, "pvz-1", pvz.GetPvz().GetId())
+	assert.Equal(t, "Казань", pvz.GetPvz().GetCity())
+	assert.True(t, now.Equal(pvz.GetPvz().GetRegistrationDate().AsTime()))
+	require.Len(t, pvz.GetReceptions(), 1)
+	assert.Equal(t, "in_progress", pvz.GetReceptions()[0].GetReception().GetStatus())
+	products := pvz.GetReceptions()[0].GetProducts()
+	require.Len(t, products, 2)
+	assert.Equal(t, "обувь", products[0].GetType())
+	assert.Equal(t, "4600000000017", products[0].GetBarcode())
+	assert.Equal(t, "order-7", products[0].GetOrderId())
+	assert.Empty(t, products[1].GetBarcode())
+	assert.Empty(t, products[1].GetOrderId())
+}
+
+func TestGetPVZList_InvalidPagination(t *testing.T) {
+	client := setupClient(t, &mockPVZStore{})
+
+	_, err := client.GetPVZList(context.Background(), &pvzv1.GetPVZListRequest{Limit: 100})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetPVZList_StoreErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"database unavailable", store.ErrDatabase, codes.Unavailable},
+		{"unexpected error", errors.New("boom"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockPVZStore{
+				fetchListFunc: func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
+					return nil, tt.err
+				},
+				fetchFunc: func(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+					return nil, tt.err
+				},
+			}
+			client := setupClient(t, mock)
+
+			_, err := client.GetPVZList(context.Background(), &pvzv1.GetPVZListRequest{})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			_, err = client.GetPVZ(context.Background(), &pvzv1.GetPVZRequest{Id: "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11"})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestGetPVZList_Cursor(t *testing.T) {
+	next := store.PVZCursor{
+		RegistrationDate: time.Date(2025, 4, 13, 10, 0, 0, 0, time.UTC),
+		ID:               "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11",
+	}
+
+	mock := &mockPVZStore{
+		fetchAfterFunc: func(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+			return []*model.PVZWithReceptions{{PVZ: model.PVZ{ID: next.ID}}}, &next, nil
+		},
+	}
+	client := setupClient(t, mock)
+
+	empty := ""
+	resp, err := client.GetPVZList(context.Background(), &pvzv1.GetPVZListRequest{Cursor: &empty, Limit: 1})
+
+	require.NoError(t, err)
+	assert.Len(t, resp.GetPvzs(), 1)
+	assert.Equal(t, next.Encode(), resp.GetNextCursor())
+}
+
+func TestGetPVZ_Success(t *testing.T) {
+	id := "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11"
+	mock := &mockPVZStore{
+		fetchFunc: func(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+			return &model.PVZWithReceptions{PVZ: model.PVZ{ID: pvzID, City: model.Moscow}}, nil
+		},
+	}
+	client := setupClient(t, mock)
+
+	resp, err := client.GetPVZ(context.Background(), &pvzv1.GetPVZRequest{Id: id})
+
+	require.NoError(t, err)
+	assert.Equal(t, id, resp.GetPvz().GetPvz().GetId())
+	assert.Equal(t, "Москва", resp.GetPvz().GetPvz().GetCity())
+}
+
+func TestGetPVZ_InvalidID(t *testing.T) {
+	client := setupClient(t, &mockPVZStore{})
+
+	_, err := client.GetPVZ(context.Background(), &pvzv1.GetPVZRequest{Id: "not-a-uuid"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetPVZ_NotFound(t *testing.T) {
+	mock := &mockPVZStore{
+		fetchFunc: func(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+			return nil, store.ErrPVZNotFound
+		},
+	}
+	client := setupClient(t, mock)
+
+	_, err := client.GetPVZ(context.Background(), &pvzv1.GetPVZRequest{Id: "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
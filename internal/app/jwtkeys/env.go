@@ -0,0 +1,67 @@
+package jwtkeys
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LegacyKeyID is the kid of the key built from JWT_SECRET.
+const LegacyKeyID = "default"
+
+// FromEnv builds a Manager from the environment.
+//
+// JWT_KEYS lists keys as comma separated kid:alg:path entries, for example
+// "2025-05:EdDSA:/run/secrets/jwt-2025-05.pem,2025-01:HS256:/run/secrets/jwt-2025-01".
+// JWT_SIGNING_KID selects the signing key and defaults to the first entry.
+// Without JWT_KEYS a single HS256 key is taken from JWT_SECRET. It is an
+// error to configure neither.
+func FromEnv() (*Manager, error) {
+	spec := strings.TrimSpace(os.Getenv("JWT_KEYS"))
+
+	if spec == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("%w: set JWT_KEYS or JWT_SECRET", ErrNoKeys)
+		}
+
+		key, err := NewHMACKey(LegacyKeyID, []byte(secret))
+		if err != nil {
+			return nil, err
+		}
+
+		return NewManager(LegacyKeyID, key)
+	}
+
+	var keys []*Key
+
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, want kid:alg:path", entry)
+		}
+
+		data, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", parts[0], err)
+		}
+
+		if parts[1] == HS256 {
+			data = []byte(strings.TrimSpace(string(data)))
+		}
+
+		key, err := ParseKey(parts[0], parts[1], data)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	signingKID := os.Getenv("JWT_SIGNING_KID")
+	if signingKID == "" {
+		signingKID = keys[0].ID
+	}
+
+	return NewManager(signingKID, keys...)
+}
@@ -0,0 +1,203 @@
+// Package jwtkeys signs and verifies JWTs with a set of keys identified by
+// kid. One key signs new tokens; every configured key verifies, so a key can
+// be rotated out without logging users out until its tokens expire.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing or verifying.
+const minRSABits = 2048
+
+var (
+	ErrNoKeys            = errors.New("no JWT keys configured")
+	ErrUnknownKey        = errors.New("unknown key ID")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match key")
+)
+
+// Key is a single signing or verification key. Keys parsed from a public
+// key can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	signKey   any
+	verifyKey any
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func NewHMACKey(kid string, secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key %q: empty HS256 secret", kid)
+	}
+
+	return &Key{ID: kid, Algorithm: HS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// ParseKey builds a key from the contents of its file: the raw secret for
+// HS256, a PEM encoded private or public key for RS256 and EdDSA.
+func ParseKey(kid, alg string, data []byte) (*Key, error) {
+	if kid == "" {
+		return nil, errors.New("key ID is empty")
+	}
+
+	switch alg {
+	case HS256:
+		return NewHMACKey(kid, data)
+	case RS256:
+		return parseRSAKey(kid, data)
+	case EdDSA:
+		return parseEdDSAKey(kid, data)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported algorithm %q", kid, alg)
+	}
+}
+
+func parseRSAKey(kid string, data []byte) (*Key, error) {
+	key := &Key{ID: kid, Algorithm: RS256}
+
+	if priv, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		key.signKey = priv
+		key.verifyKey = &priv.PublicKey
+	} else if pub, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		key.verifyKey = pub
+	} else {
+		return nil, fmt.Errorf("key %q: invalid RSA PEM: %w", kid, err)
+	}
+
+	if bits := key.verifyKey.(*rsa.PublicKey).N.BitLen(); bits < minRSABits {
+		return nil, fmt.Errorf("key %q: RSA key is %d bits, at least %d required", kid, bits, minRSABits)
+	}
+
+	return key, nil
+}
+
+func parseEdDSAKey(kid string, data []byte) (*Key, error) {
+	key := &Key{ID: kid, Algorithm: EdDSA}
+
+	if priv, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		key.signKey = priv
+		key.verifyKey = priv.(crypto.Signer).Public()
+	} else if pub, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		key.verifyKey = pub
+	} else {
+		return nil, fmt.Errorf("key %q: invalid Ed25519 PEM: %w", kid, err)
+	}
+
+	if _, ok := key.verifyKey.(ed25519.PublicKey); !ok {
+		return nil, fmt.Errorf("key %q: not an Ed25519 key", kid)
+	}
+
+	return key, nil
+}
+
+type Manager struct {
+	signing *Key
+	keys    map[string]*Key
+	methods []string
+}
+
+// NewManager returns a manager that signs with the key signingKID and
+// verifies with any of keys.
+func NewManager(signingKID string, keys ...*Key) (*Manager, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	m := &Manager{keys: make(map[string]*Key, len(keys))}
+	methods := make(map[string]bool)
+
+	for _, k := range keys {
+		if _, ok := m.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", k.ID)
+		}
+
+		m.keys[k.ID] = k
+
+		if !methods[k.Algorithm] {
+			methods[k.Algorithm] = true
+			m.methods = append(m.methods, k.Algorithm)
+		}
+	}
+
+	signing, ok := m.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKID)
+	}
+
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKID)
+	}
+
+	m.signing = signing
+
+	return m, nil
+}
+
+// Sign signs claims with the current signing key and records its kid in
+// the token header.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(m.signing.Algorithm), claims)
+	token.Header["kid"] = m.signing.ID
+
+	return token.SignedString(m.signing.signKey)
+}
+
+// Parse verifies a token with the key named by its kid header. The token
+// must use that key's algorithm, which rules out alg=none and tricks like
+// passing an RSA public key off as an HMAC secret.
+func (m *Manager) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(
+		tokenString,
+		m.keyFunc,
+		jwt.WithValidMethods(m.methods),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+func (m *Manager) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+
+	return key.verifyKey, nil
+}
+
+// sortedKeys returns the keys ordered by kid.
+func (m *Manager) sortedKeys() []*Key {
+	keys := make([]*Key, 0, len(m.keys))
+
+	for _, k := range m.keys {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys
+}
@@ -0,0 +1,248 @@
+package jwtkeys_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"pvz_server/internal/app/jwtkeys"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func privatePEM(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func newRSAKey(t *testing.T, kid string) (*jwtkeys.Key, *rsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := jwtkeys.ParseKey(kid, jwtkeys.RS256, privatePEM(t, priv))
+	require.NoError(t, err)
+
+	return key, priv
+}
+
+func newEdDSAKey(t *testing.T, kid string) (*jwtkeys.Key, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key, err := jwtkeys.ParseKey(kid, jwtkeys.EdDSA, privatePEM(t, priv))
+	require.NoError(t, err)
+
+	return key, priv
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"role": "employee",
+		"exp":  jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func TestManager_SignAndParse(t *testing.T) {
+	hmacKey, err := jwtkeys.NewHMACKey("hs", []byte("secret"))
+	require.NoError(t, err)
+	rsaKey, _ := newRSAKey(t, "rs")
+	edKey, _ := newEdDSAKey(t, "ed")
+
+	for _, kid := range []string{"hs", "rs", "ed"} {
+		t.Run(kid, func(t *testing.T) {
+			m, err := jwtkeys.NewManager(kid, hmacKey, rsaKey, edKey)
+			require.NoError(t, err)
+
+			signed, err := m.Sign(claims())
+			require.NoError(t, err)
+
+			token, err := m.Parse(signed)
+			require.NoError(t, err)
+			assert.Equal(t, kid, token.Header["kid"])
+			assert.Equal(t, "employee", token.Claims.(jwt.MapClaims)["role"])
+		})
+	}
+}
+
+func TestManager_RotationKeepsOldTokensValid(t *testing.T) {
+	oldKey, _ := newEdDSAKey(t, "2025-01")
+	newKey, _ := newEdDSAKey(t, "2025-05")
+
+	before, err := jwtkeys.NewManager("2025-01", oldKey)
+	require.NoError(t, err)
+
+	signed, err := before.Sign(claims())
+	require.NoError(t, err)
+
+	after, err := jwtkeys.NewManager("2025-05", newKey, oldKey)
+	require.NoError(t, err)
+
+	_, err = after.Parse(signed)
+	assert.NoError(t, err)
+
+	retired, err := jwtkeys.NewManager("2025-05", newKey)
+	require.NoError(t, err)
+
+	_, err = retired.Parse(signed)
+	assert.ErrorIs(t, err, jwtkeys.ErrUnknownKey)
+}
+
+func TestManager_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, priv := newRSAKey(t, "rs")
+	hmacKey, err := jwtkeys.NewHMACKey("hs", []byte("secret"))
+	require.NoError(t, err)
+
+	m, err := jwtkeys.NewManager("rs", rsaKey, hmacKey)
+	require.NoError(t, err)
+
+	// HS256 keyed with the published RSA public key, claiming the RSA kid.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = "rs"
+	signed, err := forged.SignedString(publicPEM(t, &priv.PublicKey))
+	require.NoError(t, err)
+
+	_, err = m.Parse(signed)
+	assert.ErrorIs(t, err, jwtkeys.ErrAlgorithmMismatch)
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims())
+	none.Header["kid"] = "rs"
+	signed, err = none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+}
+
+func TestManager_RejectsTokenWithoutExpiry(t *testing.T) {
+	key, err := jwtkeys.NewHMACKey("hs", []byte("secret"))
+	require.NoError(t, err)
+
+	m, err := jwtkeys.NewManager("hs", key)
+	require.NoError(t, err)
+
+	signed, err := m.Sign(jwt.MapClaims{"role": "employee"})
+	require.NoError(t, err)
+
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+}
+
+func TestNewManager_Invalid(t *testing.T) {
+	_, err := jwtkeys.NewManager("any")
+	assert.ErrorIs(t, err, jwtkeys.ErrNoKeys)
+
+	_, err = jwtkeys.NewHMACKey("hs", nil)
+	assert.Error(t, err)
+
+	_, priv := newRSAKey(t, "rs")
+	verifyOnly, err := jwtkeys.ParseKey("rs", jwtkeys.RS256, publicPEM(t, &priv.PublicKey))
+	require.NoError(t, err)
+	assert.False(t, verifyOnly.CanSign())
+
+	_, err = jwtkeys.NewManager("rs", verifyOnly)
+	assert.Error(t, err)
+
+	_, err = jwtkeys.NewManager("missing", verifyOnly)
+	assert.Error(t, err)
+}
+
+func TestManager_JWKS(t *testing.T) {
+	hmacKey, err := jwtkeys.NewHMACKey("hs", []byte("secret"))
+	require.NoError(t, err)
+	rsaKey, _ := newRSAKey(t, "rs")
+	edKey, _ := newEdDSAKey(t, "ed")
+
+	m, err := jwtkeys.NewManager("ed", hmacKey, rsaKey, edKey)
+	require.NoError(t, err)
+
+	set := m.JWKS()
+
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "ed", set.Keys[0].Kid)
+	assert.Equal(t, "OKP", set.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", set.Keys[0].Crv)
+	assert.NotEmpty(t, set.Keys[0].X)
+	assert.Equal(t, "rs", set.Keys[1].Kid)
+	assert.Equal(t, "RSA", set.Keys[1].Kty)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("NoKeys", func(t *testing.T) {
+		t.Setenv("JWT_KEYS", "")
+		t.Setenv("JWT_SECRET", "")
+
+		_, err := jwtkeys.FromEnv()
+		assert.ErrorIs(t, err, jwtkeys.ErrNoKeys)
+	})
+
+	t.Run("LegacySecret", func(t *testing.T) {
+		t.Setenv("JWT_KEYS", "")
+		t.Setenv("JWT_SECRET", "secret")
+
+		m, err := jwtkeys.FromEnv()
+		require.NoError(t, err)
+
+		signed, err := m.Sign(claims())
+		require.NoError(t, err)
+
+		token, err := m.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, jwtkeys.LegacyKeyID, token.Header["kid"])
+	})
+
+	t.Run("KeyFiles", func(t *testing.T) {
+		dir := t.TempDir()
+		_, edPriv := newEdDSAKey(t, "ed")
+
+		edPath := filepath.Join(dir, "ed.pem")
+		hsPath := filepath.Join(dir, "hs")
+		require.NoError(t, os.WriteFile(edPath, privatePEM(t, edPriv), 0o600))
+		require.NoError(t, os.WriteFile(hsPath, []byte("old-secret\n"), 0o600))
+
+		t.Setenv("JWT_KEYS", "2025-05:EdDSA:"+edPath+",2025-01:HS256:"+hsPath)
+		t.Setenv("JWT_SIGNING_KID", "")
+
+		m, err := jwtkeys.FromEnv()
+		require.NoError(t, err)
+
+		signed, err := m.Sign(claims())
+		require.NoError(t, err)
+
+		token, err := m.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-05", token.Header["kid"])
+		assert.Equal(t, jwtkeys.EdDSA, token.Method.Alg())
+	})
+
+	t.Run("InvalidEntry", func(t *testing.T) {
+		t.Setenv("JWT_KEYS", "broken")
+
+		_, err := jwtkeys.FromEnv()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "pvz"
+
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests by method, route and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	HTTPRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	PVZCreatedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pvz_created_total",
+			Help:      "Total number of created PVZs.",
+		},
+	)
+
+	ReceptionsCreatedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "receptions_created_total",
+			Help:      "Total number of opened receptions.",
+		},
+	)
+
+	ReceptionsClosedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "receptions_closed_total",
+			Help:      "Total number of closed receptions.",
+		},
+	)
+
+	ProductsAddedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "products_added_total",
+			Help:      "Total number of products added to receptions by type.",
+		},
+		[]string{"type"},
+	)
+
+	ProductsDeletedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "products_deleted_total",
+			Help:      "Total number of products deleted from receptions by type.",
+		},
+		[]string{"type"},
+	)
+
+	OutboxPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "outbox_published_total",
+			Help:      "Total number of outbox events published by type.",
+		},
+		[]string{"type"},
+	)
+
+	OutboxFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "outbox_failures_total",
+			Help:      "Total number of failed outbox event deliveries by type.",
+		},
+		[]string{"type"},
+	)
+
+	WebhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Total number of webhook delivery attempts by outcome: succeeded, retry or dead.",
+		},
+		[]string{"outcome"},
+	)
+)
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server serves /metrics on its own port so it is not exposed together
+// with the public API.
+type Server struct {
+	httpServer *http.Server
+}
+
+func NewServer() *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &Server{
+		httpServer: &http.Server{Handler: mux},
+	}
+}
+
+func (s *Server) Run(addr string) error {
+	s.httpServer.Addr = addr
+
+	err := s.httpServer.ListenAndServe()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
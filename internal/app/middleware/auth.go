@@ -1,48 +1,57 @@
 package middleware
 
 import (
-	"net/http"
-	"os"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
-
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts requests with a valid access token that has not
+// been revoked.
+func AuthMiddleware(keys *jwtkeys.Manager, revocations store.AccessTokenChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "unauthorized"})
+			apierror.Respond(c, apierror.ErrUnauthorized)
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "unauthorized"})
+		token, err := utils.ParseAccessToken(keys, tokenString)
+		if err != nil {
+			apierror.Respond(c, apierror.ErrUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "unauthorized"})
+		revoked, err := revocations.IsAccessTokenRevoked(c.Request.Context(), token.JTI)
+		if err != nil {
+			apierror.Respond(c, err)
 			return
 		}
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "unauthorized"})
+		if revoked {
+			apierror.Respond(c, apierror.ErrUnauthorized)
 			return
 		}
 
-		c.Set("role", role)
+		c.Set("role", token.Role)
+		c.Set("userID", token.UserID)
+		c.Set("jti", token.JTI)
+		c.Set("tokenExpiresAt", token.ExpiresAt)
+
+		// Stores attribute audit events to the actor carried by the context.
+		c.Request = c.Request.WithContext(store.WithActor(c.Request.Context(), model.Actor{
+			UserID:    token.UserID,
+			Role:      token.Role,
+			RequestID: c.GetString(apierror.RequestIDKey),
+		}))
+
 		c.Next()
 	}
 }
@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRevocations struct {
+	revoked map[string]bool
+}
+
+func (m *mockRevocations) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return m.revoked[jti], nil
+}
+
+var testKeys = newTestKeys()
+
+func newTestKeys() *jwtkeys.Manager {
+	key, _ := jwtkeys.NewHMACKey("test", []byte("test-secret"))
+	keys, _ := jwtkeys.NewManager("test", key)
+	return keys
+}
+
+func setupAuthRouter(revocations *mockRevocations) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.AuthMiddleware(testKeys, revocations))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("role"))
+	})
+	return r
+}
+
+func getWithToken(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_ValidToken(t *testing.T) {
+	r := setupAuthRouter(&mockRevocations{})
+	token, _ := utils.GenerateJWT(testKeys, utils.NewAccessToken("u-1", "employee"))
+
+	w := getWithToken(r, token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "employee", w.Body.String())
+}
+
+func TestAuthMiddleware_RevokedToken(t *testing.T) {
+	access := utils.NewAccessToken("u-1", "employee")
+	r := setupAuthRouter(&mockRevocations{revoked: map[string]bool{access.JTI: true}})
+	token, _ := utils.GenerateJWT(testKeys, access)
+
+	w := getWithToken(r, token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthMiddleware_TokenWithoutJTI(t *testing.T) {
+	r := setupAuthRouter(&mockRevocations{})
+	token, _ := testKeys.Sign(jwt.MapClaims{
+		"user_id": "u-1",
+		"role":    "employee",
+		"exp":     jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+
+	w := getWithToken(r, token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthMiddleware_MissingHeader(t *testing.T) {
+	r := setupAuthRouter(&mockRevocations{})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthMiddleware_AttachesActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.AuthMiddleware(testKeys, &mockRevocations{}))
+
+	var actor model.Actor
+	r.GET("/", func(c *gin.Context) {
+		actor = store.ActorFromContext(c.Request.Context())
+	})
+
+	token, _ := utils.GenerateJWT(testKeys, utils.NewAccessToken("u-1", "moderator"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, model.Actor{UserID: "u-1", Role: "moderator", RequestID: "req-42"}, actor)
+}
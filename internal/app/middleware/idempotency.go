@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader marks responses replayed from an earlier request.
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long a response is replayed for.
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// idempotencyLease is how long a key stays reserved for a request that
+	// has not produced a response. A retry after it takes the key over, so a
+	// crashed server does not block the key until the TTL runs out.
+	idempotencyLease = time.Minute
+
+	maxIdempotencyKeyLen = 255
+	// maxIdempotentBodyBytes bounds the body read to fingerprint a request.
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+var (
+	errInvalidIdempotencyKey = apierror.New(http.StatusBadRequest, "invalid_idempotency_key", "invalid Idempotency-Key")
+	errRequestTooLarge       = apierror.New(http.StatusRequestEntityTooLarge, "request_too_large", "request body is too large")
+)
+
+// Idempotency makes a request with an Idempotency-Key header safe to retry:
+// the first response is stored for ttl and returned to every retry with the
+// same key, method, path and body without running the handler again. Reusing
+// the key for a different request is rejected. Server errors and panics are
+// not stored, so a retry after one runs the request again. Requests without
+// the header are passed through. It must run after AuthMiddleware, keys are
+// per user.
+func Idempotency(keys store.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			apierror.Respond(c, errInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.Respond(c, errRequestTooLarge)
+				return
+			}
+
+			apierror.Respond(c, apierror.ErrInvalidRequest)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetString("userID")
+		now := time.Now()
+
+		stored, err := keys.ReserveIdempotencyKey(c.Request.Context(), store.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   now.Add(ttl),
+			LockedUntil: now.Add(idempotencyLease),
+		})
+
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		if stored != nil {
+			c.Header(IdempotentReplayHeader, "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		// The outcome must be recorded even if the client has gone away.
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		defer func() {
+			if r := recover(); r != nil {
+				if err := keys.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+					_ = c.Error(err)
+				}
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		if rec.Status() >= http.StatusInternalServerError {
+			if err := keys.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+				_ = c.Error(err)
+			}
+			return
+		}
+
+		err = keys.SaveIdempotentResponse(ctx, userID, key, store.StoredResponse{
+			Status:      rec.Status(),
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
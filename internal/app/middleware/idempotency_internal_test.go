@@ -0,0 +1,183 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/middleware"
+	"pvz_server/internal/app/store/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupIdempotencyRouter counts handler runs and answers with the given
+// status and a body that changes on every run.
+func setupIdempotencyRouter(keys *memory.Store, status int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+
+	r.POST("/items", middleware.Idempotency(keys, time.Hour), func(c *gin.Context) {
+		*calls++
+		c.JSON(status, gin.H{"call": *calls})
+	})
+
+	return r
+}
+
+func postItem(r *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusCreated, &calls)
+
+	first := postItem(r, "u-1", "k-1", `{"type":"обувь"}`)
+	retry := postItem(r, "u-1", "k-1", `{"type":"обувь"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayHeader))
+	assert.Empty(t, first.Header().Get(middleware.IdempotentReplayHeader))
+
+	// Keys belong to their user.
+	other := postItem(r, "u-2", "k-1", `{"type":"обувь"}`)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, `{"call":2}`, other.Body.String())
+}
+
+func TestIdempotency_RejectsDifferentRequest(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusCreated, &calls)
+
+	postItem(r, "u-1", "k-1", `{"type":"обувь"}`)
+	w := postItem(r, "u-1", "k-1", `{"type":"одежда"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"idempotency_key_mismatch"`)
+}
+
+func TestIdempotency_ReplaysClientErrors(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusConflict, &calls)
+
+	postItem(r, "u-1", "k-1", `{}`)
+	w := postItem(r, "u-1", "k-1", `{}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestIdempotency_RetriesServerErrors(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusServiceUnavailable, &calls)
+
+	postItem(r, "u-1", "k-1", `{}`)
+	w := postItem(r, "u-1", "k-1", `{}`)
+
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, w.Header().Get(middleware.IdempotentReplayHeader))
+}
+
+func TestIdempotency_KeyInUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	r.POST("/items", middleware.Idempotency(memory.New(), time.Hour), func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postItem(r, "u-1", "k-1", `{}`)
+	}()
+
+	<-entered
+	w := postItem(r, "u-1", "k-1", `{}`)
+	close(release)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"idempotency_key_in_use"`)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusCreated, &calls)
+
+	postItem(r, "u-1", "", `{}`)
+	postItem(r, "u-1", "", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeyTooLong(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusCreated, &calls)
+
+	w := postItem(r, "u-1", strings.Repeat("k", 256), `{}`)
+
+	assert.Zero(t, calls)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_idempotency_key"`)
+}
+
+func TestIdempotency_ReleasesKeyOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+
+	var calls int
+	r.POST("/items", middleware.Idempotency(memory.New(), time.Hour), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	first := postItem(r, "u-1", "k-1", `{}`)
+	retry := postItem(r, "u-1", "k-1", `{}`)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, 2, calls, "the retry runs instead of finding the key in use")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	var calls int
+	r := setupIdempotencyRouter(memory.New(), http.StatusCreated, &calls)
+
+	w := postItem(r, "u-1", "k-1", `"`+strings.Repeat("x", 1<<20)+`"`)
+
+	assert.Zero(t, calls)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"request_too_large"`)
+}
@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/metrics"
+	"pvz_server/internal/app/middleware"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware_RecordsRouteAndStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.MetricsMiddleware())
+	r.GET("/pvz/:pvzId", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	counter := metrics.HTTPRequestsTotal.WithLabelValues("GET", "/pvz/:pvzId", "418")
+	before := testutil.ToFloat64(counter)
+
+	req, _ := http.NewRequest("GET", "/pvz/123", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestMetricsMiddleware_UnmatchedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.MetricsMiddleware())
+
+	counter := metrics.HTTPRequestsTotal.WithLabelValues("GET", "unmatched", "404")
+	before := testutil.ToFloat64(counter)
+
+	req, _ := http.NewRequest("GET", "/does-not-exist", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through only if the caller's role holds
+// perm under policy. It must run after AuthMiddleware.
+func RequirePermission(policy *authz.Policy, perm authz.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := model.UserRole(c.GetString("role"))
+
+		if !policy.Allows(role, perm) {
+			apierror.Respond(c, apierror.ErrAccessDenied)
+			return
+		}
+
+		c.Next()
+	}
+}
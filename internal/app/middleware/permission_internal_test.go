@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/authz"
+	"pvz_server/internal/app/middleware"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPermissionRouter(role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	})
+	r.POST("/pvz", middleware.RequirePermission(authz.DefaultPolicy(), authz.PVZCreate), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	return r
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		role   string
+		status int
+	}{
+		{"moderator", http.StatusCreated},
+		{"admin", http.StatusCreated},
+		{"employee", http.StatusForbidden},
+		{"auditor", http.StatusForbidden},
+		{"guest", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			r := setupPermissionRouter(tt.role)
+
+			req, _ := http.NewRequest("POST", "/pvz", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
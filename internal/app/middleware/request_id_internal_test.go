@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/middleware"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.GET("/fail", func(c *gin.Context) {
+		apierror.Respond(c, apierror.ErrAccessDenied)
+	})
+	return r
+}
+
+func TestRequestID_KeepsClientHeader(t *testing.T) {
+	r := setupRequestIDRouter()
+
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, "req-42", w.Header().Get(middleware.RequestIDHeader))
+	assert.Equal(t, "req-42", resp.RequestID)
+}
+
+func TestRequestID_GeneratesMissingHeader(t *testing.T) {
+	r := setupRequestIDRouter()
+
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.NotEmpty(t, resp.RequestID)
+	assert.Equal(t, resp.RequestID, w.Header().Get(middleware.RequestIDHeader))
+}
@@ -0,0 +1,7 @@
+package model
+
+// PVZAssignment allows an employee to run receptions at a PVZ.
+type PVZAssignment struct {
+	UserID string `json:"userId"`
+	PVZID  string `json:"pvzId"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditPVZCreated      AuditAction = "pvz.created"
+	AuditReceptionOpened AuditAction = "reception.opened"
+	AuditReceptionClosed AuditAction = "reception.closed"
+	AuditProductAdded    AuditAction = "product.added"
+	AuditProductDeleted  AuditAction = "product.deleted"
+)
+
+// Actor is the caller on whose behalf a change is made.
+type Actor struct {
+	UserID    string
+	Role      string
+	RequestID string
+}
+
+// AuditEvent records a single state change. Before and After hold the
+// affected entity as returned by the API; either is empty when the entity
+// did not exist on that side of the change.
+type AuditEvent struct {
+	ID          string          `json:"id"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	ActorID     string          `json:"actorId"`
+	ActorRole   string          `json:"actorRole"`
+	Action      AuditAction     `json:"action"`
+	PVZID       string          `json:"pvzId"`
+	ReceptionID string          `json:"receptionId,omitempty"`
+	ProductID   string          `json:"productId,omitempty"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	RequestID   string          `json:"requestId,omitempty"`
+	// Reason is the explanation the caller gave for the change, if any.
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventReceptionOpened EventType = "reception.opened"
+	EventReceptionClosed EventType = "reception.closed"
+	EventProductAdded    EventType = "product.added"
+	EventProductDeleted  EventType = "product.deleted"
+)
+
+// Event is a reception lifecycle change published to downstream systems.
+// Data holds the affected reception or product as returned by the API.
+// Delivery is at-least-once, consumers deduplicate by ID.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	PVZID      string          `json:"pvzId"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+	// Seq orders events across all PVZs. Events read for streams carry
+	// their stream seq, which follows commit order, and use it as their ID.
+	Seq int64 `json:"-"`
+	// Attempts counts failed deliveries so far.
+	Attempts int `json:"-"`
+}
@@ -10,15 +10,32 @@ const (
 	Shoes       ProductType = "обувь"
 )
 
-var AllowedProductTypes = map[ProductType]bool{
-	"электроника": true,
-	"одежда":      true,
-	"обувь":       true,
+// ProductTypeEntry is a product type of the catalogue. Products can be
+// added only with the code of an active type; Names holds the display name
+// per locale, e.g. "ru" or "en".
+type ProductTypeEntry struct {
+	Code           ProductType       `json:"code"`
+	Names          map[string]string `json:"names"`
+	Fragile        bool              `json:"fragile"`
+	MaxWeightGrams *int              `json:"maxWeightGrams,omitempty"`
+	Active         bool              `json:"active"`
+	CreatedAt      time.Time         `json:"createdAt"`
 }
 
+// DefaultProductTypes seed the product type catalogue of a new database.
+var DefaultProductTypes = []ProductTypeEntry{
+	{Code: Electronics, Names: map[string]string{"ru": "Электроника", "en": "Electronics"}, Fragile: true},
+	{Code: Clothing, Names: map[string]string{"ru": "Одежда", "en": "Clothing"}},
+	{Code: Shoes, Names: map[string]string{"ru": "Обувь", "en": "Shoes"}},
+}
+
+// Product is an item accepted in a reception. Barcode and OrderID are
+// optional; a barcode is unique within its reception.
 type Product struct {
 	ID          string      `json:"id"`
 	DateTime    time.Time   `json:"dateTime"`
 	Type        ProductType `json:"type"`
 	ReceptionID string      `json:"receptionId"`
+	Barcode     string      `json:"barcode,omitempty"`
+	OrderID     string      `json:"orderId,omitempty"`
 }
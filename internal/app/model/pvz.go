@@ -12,10 +12,17 @@ const (
 	Kazan  City = "Казань"
 )
 
-var AllowedCities = map[City]bool{
-	Moscow: true,
-	SPB:    true,
-	Kazan:  true,
+// DefaultCities seed the city catalogue of a new database.
+var DefaultCities = []City{Moscow, SPB, Kazan}
+
+// CityEntry is a city of the catalogue. PVZs can be created only in active
+// cities; Aliases are former names, still accepted for the current one.
+type CityEntry struct {
+	ID        string    `json:"id"`
+	Name      City      `json:"name"`
+	Aliases   []City    `json:"aliases"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 type PVZ struct {
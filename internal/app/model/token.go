@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token. Only
+// the hash of the token is stored. Every token obtained by rotation shares
+// the FamilyID of the token issued at login.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	Role      UserRole
+	TokenHash string
+	// AccessJTI and AccessExpiresAt identify the access token issued
+	// together with this refresh token, so it can be revoked with the family.
+	AccessJTI       string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+}
@@ -0,0 +1,25 @@
+package model
+
+type UserRole string
+
+const (
+	Employee  UserRole = "employee"
+	Moderator UserRole = "moderator"
+	Admin     UserRole = "admin"
+	// Auditor has read-only access.
+	Auditor UserRole = "auditor"
+)
+
+var AllowedUserRoles = map[UserRole]bool{
+	Employee:  true,
+	Moderator: true,
+	Admin:     true,
+	Auditor:   true,
+}
+
+type User struct {
+	ID           string   `json:"id"`
+	Email        string   `json:"email"`
+	PasswordHash string   `json:"-"`
+	Role         UserRole `json:"role"`
+}
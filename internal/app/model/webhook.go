@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AllowedEventTypes lists the events a webhook can subscribe to.
+var AllowedEventTypes = map[EventType]bool{
+	EventReceptionOpened: true,
+	EventReceptionClosed: true,
+	EventProductAdded:    true,
+	EventProductDeleted:  true,
+}
+
+type WebhookSubscription struct {
+	ID         string      `json:"id"`
+	URL        string      `json:"url"`
+	Secret     string      `json:"-"`
+	EventTypes []EventType `json:"eventTypes"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead marks a delivery that ran out of attempts. It is only
+	// retried when replayed.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+var AllowedDeliveryStatuses = map[DeliveryStatus]bool{
+	DeliveryPending:   true,
+	DeliverySucceeded: true,
+	DeliveryDead:      true,
+}
+
+// WebhookDelivery is one event sent to one subscription. Payload is the
+// request body, the event as published by the outbox.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        string          `json:"eventId"`
+	EventType      EventType       `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PublisherFromEnv builds the publisher selected by OUTBOX_PUBLISHER:
+//
+//	stdout                 JSON lines on standard output
+//	file:/var/log/pvz.log  JSON lines appended to a file
+//	http://host/path       POST to a webhook, https works as well
+//
+// An empty value returns nil: no extra destination is added, and the relay
+// still delivers events to webhook subscriptions.
+func PublisherFromEnv() (Publisher, error) {
+	spec := strings.TrimSpace(os.Getenv("OUTBOX_PUBLISHER"))
+
+	switch {
+	case spec == "":
+		return nil, nil
+	case spec == "stdout":
+		return NewWriterPublisher(os.Stdout), nil
+	case strings.HasPrefix(spec, "file:"):
+		return OpenFile(strings.TrimPrefix(spec, "file:"))
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return NewWebhookPublisher(spec, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown OUTBOX_PUBLISHER %q", spec)
+	}
+}
@@ -0,0 +1,132 @@
+// Package outbox delivers events written to the store outbox by mutations.
+// The relay claims pending events, hands them to a Publisher and records the
+// outcome; an event is retried until a publisher accepts it, so every event
+// is delivered at least once.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"pvz_server/internal/app/model"
+	"sync"
+	"time"
+)
+
+// Publisher delivers a single event. A returned error schedules a retry.
+type Publisher interface {
+	Publish(ctx context.Context, ev model.Event) error
+}
+
+// WriterPublisher writes events as JSON lines, for example to stdout or an
+// append-only file.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// OpenFile appends events to the file at path, creating it if needed.
+func OpenFile(path string) (*WriterPublisher, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterPublisher(f), nil
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, ev model.Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying writer if it is closable.
+func (p *WriterPublisher) Close() error {
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+const webhookTimeout = 10 * time.Second
+
+// WebhookPublisher posts every event as JSON to a single URL. Any response
+// other than 2xx counts as a failed delivery.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher uses client for requests, or a client with a 10s
+// timeout when client is nil.
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	return &WebhookPublisher{url: url, client: client}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, ev model.Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", ev.ID)
+	req.Header.Set("X-Event-Type", string(ev.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Fanout publishes every event to all of its publishers. If any of them
+// fails the event is retried on all, so each publisher must tolerate
+// duplicates.
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, ev model.Event) error {
+	var errs []error
+
+	for _, p := range f {
+		if err := p.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
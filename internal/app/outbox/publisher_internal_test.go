@@ -0,0 +1,100 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/outbox"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = model.Event{
+	ID:         "e-1",
+	Type:       model.EventReceptionClosed,
+	PVZID:      "p-1",
+	OccurredAt: time.Date(2025, 5, 6, 12, 0, 0, 0, time.UTC),
+	Data:       json.RawMessage(`{"status":"close"}`),
+}
+
+const testEventJSON = `{"id":"e-1","type":"reception.closed","pvzId":"p-1","occurredAt":"2025-05-06T12:00:00Z","data":{"status":"close"}}`
+
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, outbox.NewWriterPublisher(&buf).Publish(context.Background(), testEvent))
+
+	assert.Equal(t, testEventJSON+"\n", buf.String())
+}
+
+func TestOpenFile_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	for range 2 {
+		pub, err := outbox.OpenFile(path)
+		require.NoError(t, err)
+		require.NoError(t, pub.Publish(context.Background(), testEvent))
+		require.NoError(t, pub.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, testEventJSON+"\n"+testEventJSON+"\n", string(data))
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	var (
+		body   []byte
+		header http.Header
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	err := outbox.NewWebhookPublisher(srv.URL, nil).Publish(context.Background(), testEvent)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, testEventJSON, string(body))
+	assert.Equal(t, "e-1", header.Get("X-Event-ID"))
+	assert.Equal(t, "reception.closed", header.Get("X-Event-Type"))
+}
+
+func TestWebhookPublisher_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := outbox.NewWebhookPublisher(srv.URL, nil).Publish(context.Background(), testEvent)
+
+	assert.ErrorContains(t, err, "500")
+}
+
+func TestPublisherFromEnv(t *testing.T) {
+	t.Setenv("OUTBOX_PUBLISHER", "")
+	pub, err := outbox.PublisherFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, pub)
+
+	t.Setenv("OUTBOX_PUBLISHER", "http://localhost:8081/events")
+	pub, err = outbox.PublisherFromEnv()
+	require.NoError(t, err)
+	assert.IsType(t, &outbox.WebhookPublisher{}, pub)
+
+	t.Setenv("OUTBOX_PUBLISHER", "kafka://broker")
+	_, err = outbox.PublisherFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"pvz_server/internal/app/metrics"
+	"pvz_server/internal/app/store"
+	"time"
+)
+
+const (
+	DefaultInterval  = time.Second
+	DefaultBatchSize = 100
+	// DefaultLease must exceed the time a batch takes to publish, otherwise
+	// another relay may claim the same events while they are in flight.
+	DefaultLease = time.Minute
+
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Relay moves events from the store outbox to a Publisher. Events are
+// claimed in the order they were written, but a failed event is retried
+// later without holding back the ones after it, so consumers must not rely
+// on delivery order.
+type Relay struct {
+	outbox    store.EventOutbox
+	publisher Publisher
+
+	Interval  time.Duration
+	BatchSize int
+	Lease     time.Duration
+}
+
+func NewRelay(outbox store.EventOutbox, publisher Publisher) *Relay {
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+		Lease:     DefaultLease,
+	}
+}
+
+// Run polls the outbox every Interval until ctx is done. A full batch is
+// followed by the next one right away so a backlog drains quickly.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.ProcessBatch(ctx)
+
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+
+		if err == nil && n == r.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch sequences committed events for streams, then publishes one
+// batch of pending events and returns how many were claimed. Sequencing here
+// keeps the stream read path free of locks.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	if err := r.outbox.SequenceEvents(ctx); err != nil {
+		return 0, err
+	}
+
+	events, err := r.outbox.ClaimEvents(ctx, r.BatchSize, r.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, ev := range events {
+		if err := r.publisher.Publish(ctx, ev); err != nil {
+			metrics.OutboxFailuresTotal.WithLabelValues(string(ev.Type)).Inc()
+
+			retryAt := time.Now().Add(Backoff(ev.Attempts + 1))
+
+			if err := r.outbox.MarkEventFailed(ctx, ev.ID, err.Error(), retryAt); err != nil {
+				return len(events), err
+			}
+
+			continue
+		}
+
+		metrics.OutboxPublishedTotal.WithLabelValues(string(ev.Type)).Inc()
+
+		// Failing here leaves the event claimed; it is published again once
+		// the lease runs out, which at-least-once delivery allows.
+		if err := r.outbox.MarkEventPublished(ctx, ev.ID); err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+// Backoff returns the delay before retrying an event that failed attempts
+// times: one second doubling up to five minutes.
+func Backoff(attempts int) time.Duration {
+	d := minBackoff
+
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, maxBackoff)
+}
@@ -0,0 +1,155 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/outbox"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/memory"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []model.Event
+	failures  int
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, ev model.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("unavailable")
+	}
+
+	p.published = append(p.published, ev)
+	return nil
+}
+
+// unsequencedOutbox fails to sequence events, as a store that has lost its
+// database would.
+type unsequencedOutbox struct {
+	*memory.Store
+}
+
+func (o unsequencedOutbox) SequenceEvents(ctx context.Context) error {
+	return store.ErrDatabase
+}
+
+func newReception(t *testing.T, s *memory.Store) string {
+	t.Helper()
+	ctx := context.Background()
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+	_, err = s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+	require.NoError(t, err)
+
+	return pvz.ID
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	s := memory.New()
+	pvzID := newReception(t, s)
+	pub := &fakePublisher{}
+
+	n, err := outbox.NewRelay(s, pub).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, pub.published, 2)
+	assert.Equal(t, model.EventReceptionOpened, pub.published[0].Type)
+	assert.Equal(t, model.EventProductAdded, pub.published[1].Type)
+	assert.Equal(t, pvzID, pub.published[1].PVZID)
+
+	n, err = outbox.NewRelay(s, pub).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, n, "published events must not be delivered again")
+}
+
+func TestRelay_RetriesFailedEvents(t *testing.T) {
+	s := memory.New()
+	newReception(t, s)
+	pub := &fakePublisher{failures: 1}
+
+	relay := outbox.NewRelay(s, pub)
+
+	_, err := relay.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pub.published, 1)
+	assert.Equal(t, model.EventProductAdded, pub.published[0].Type)
+
+	// The failed event waits for its backoff.
+	n, err := relay.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	time.Sleep(outbox.Backoff(1))
+
+	_, err = relay.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pub.published, 2)
+	assert.Equal(t, model.EventReceptionOpened, pub.published[1].Type)
+}
+
+func TestRelay_SequencesBeforeClaiming(t *testing.T) {
+	s := memory.New()
+	newReception(t, s)
+	pub := &fakePublisher{}
+
+	n, err := outbox.NewRelay(unsequencedOutbox{s}, pub).ProcessBatch(context.Background())
+
+	require.ErrorIs(t, err, store.ErrDatabase)
+	assert.Zero(t, n)
+	assert.Empty(t, pub.published)
+
+	// Nothing was claimed, so a healthy relay publishes every event.
+	n, err = outbox.NewRelay(s, pub).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestRelay_Run(t *testing.T) {
+	s := memory.New()
+	newReception(t, s)
+	pub := &fakePublisher{}
+
+	relay := outbox.NewRelay(s, pub)
+	relay.Interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		pub.mu.Lock()
+		defer pub.mu.Unlock()
+		return len(pub.published) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outbox.Backoff(1))
+	assert.Equal(t, 2*time.Second, outbox.Backoff(2))
+	assert.Equal(t, 8*time.Second, outbox.Backoff(4))
+	assert.Equal(t, 5*time.Minute, outbox.Backoff(20))
+}
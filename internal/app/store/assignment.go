@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AssignPVZ lets an employee work at a PVZ. Assigning twice is a no-op.
+func (s *Store) AssignPVZ(ctx context.Context, userID, pvzID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	if err := ensureEmployee(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO employee_pvz (user_id, pvz_id, assigned_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, pvz_id) DO NOTHING`,
+		userID,
+		pvzID,
+		time.Now(),
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) UnassignPVZ(ctx context.Context, userID, pvzID string) error {
+	if uuid.Validate(userID) != nil || uuid.Validate(pvzID) != nil {
+		return ErrAssignmentNotFound
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM employee_pvz WHERE user_id = $1 AND pvz_id = $2`,
+		userID,
+		pvzID,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if n == 0 {
+		return ErrAssignmentNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) ListAssignedPVZs(ctx context.Context, userID string) ([]model.PVZ, error) {
+	if uuid.Validate(userID) != nil {
+		return nil, ErrUserNotFound
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT p.id, p.registration_date, p.city
+		FROM employee_pvz a
+		JOIN pvz p ON p.id = a.pvz_id
+		WHERE a.user_id = $1
+		ORDER BY p.registration_date, p.id`,
+		userID,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	pvzs := []model.PVZ{}
+
+	for rows.Next() {
+		var p model.PVZ
+
+		if err := rows.Scan(&p.ID, &p.RegistrationDate, &p.City); err != nil {
+			return nil, ErrDatabase
+		}
+
+		pvzs = append(pvzs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return pvzs, nil
+}
+
+// IsAssigned reports whether the user works at the PVZ. It returns
+// ErrPVZNotFound for an unknown PVZ, so callers can tell a missing PVZ from
+// one the user is not assigned to.
+func (s *Store) IsAssigned(ctx context.Context, userID, pvzID string) (bool, error) {
+	if uuid.Validate(pvzID) != nil {
+		return false, ErrPVZNotFound
+	}
+
+	if uuid.Validate(userID) != nil {
+		userID = uuid.Nil.String()
+	}
+
+	var exists, assigned bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT
+			EXISTS (SELECT 1 FROM pvz WHERE id = $2),
+			EXISTS (SELECT 1 FROM employee_pvz WHERE user_id = $1 AND pvz_id = $2)`,
+		userID,
+		pvzID,
+	).Scan(&exists, &assigned)
+
+	if err != nil {
+		return false, ErrDatabase
+	}
+
+	if !exists {
+		return false, ErrPVZNotFound
+	}
+
+	return assigned, nil
+}
+
+func ensureEmployee(ctx context.Context, tx *sql.Tx, userID string) error {
+	if uuid.Validate(userID) != nil {
+		return ErrUserNotFound
+	}
+
+	var role model.UserRole
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT role FROM users WHERE id = $1`,
+		userID,
+	).Scan(&role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if role != model.Employee {
+		return ErrUserNotEmployee
+	}
+
+	return nil
+}
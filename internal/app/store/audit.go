@@ -0,0 +1,221 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pvz_server/internal/app/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type actorKey struct{}
+
+// WithActor attaches the caller to ctx so mutations can attribute their
+// audit events.
+func WithActor(ctx context.Context, actor model.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the caller attached by WithActor. Changes made
+// without one are attributed to the zero Actor.
+func ActorFromContext(ctx context.Context) model.Actor {
+	actor, _ := ctx.Value(actorKey{}).(model.Actor)
+	return actor
+}
+
+// AuditFilter narrows FetchAuditEvents. Zero fields do not filter.
+type AuditFilter struct {
+	PVZID   string
+	ActorID string
+	From    *time.Time
+	To      *time.Time
+	Page    int
+	Limit   int
+}
+
+// AuditRecord describes a change to be written to the audit log.
+type AuditRecord struct {
+	Action      model.AuditAction
+	PVZID       string
+	ReceptionID string
+	ProductID   string
+	Before      any
+	After       any
+	Reason      string
+}
+
+// NewAuditEvent attributes rec to the actor in ctx.
+func NewAuditEvent(ctx context.Context, rec AuditRecord, now time.Time) (*model.AuditEvent, error) {
+	actor := ActorFromContext(ctx)
+
+	ev := &model.AuditEvent{
+		ID:          uuid.NewString(),
+		OccurredAt:  now,
+		ActorID:     actor.UserID,
+		ActorRole:   actor.Role,
+		Action:      rec.Action,
+		PVZID:       rec.PVZID,
+		ReceptionID: rec.ReceptionID,
+		ProductID:   rec.ProductID,
+		RequestID:   actor.RequestID,
+		Reason:      rec.Reason,
+	}
+
+	var err error
+
+	if rec.Before != nil {
+		if ev.Before, err = json.Marshal(rec.Before); err != nil {
+			return nil, err
+		}
+	}
+
+	if rec.After != nil {
+		if ev.After, err = json.Marshal(rec.After); err != nil {
+			return nil, err
+		}
+	}
+
+	return ev, nil
+}
+
+// recordAudit writes rec inside tx, so the change and its audit event are
+// committed or rolled back together.
+func recordAudit(ctx context.Context, tx *sql.Tx, rec AuditRecord, now time.Time) error {
+	ev, err := NewAuditEvent(ctx, rec, now)
+	if err != nil {
+		return ErrDatabase
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO audit_events
+			(id, occurred_at, actor_id, actor_role, action, pvz_id, reception_id, product_id, before, after, request_id, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		ev.ID,
+		ev.OccurredAt,
+		ev.ActorID,
+		ev.ActorRole,
+		ev.Action,
+		ev.PVZID,
+		nullString(ev.ReceptionID),
+		nullString(ev.ProductID),
+		nullJSON(ev.Before),
+		nullJSON(ev.After),
+		ev.RequestID,
+		ev.Reason,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// FetchAuditEvents returns matching events, newest first.
+func (s *Store) FetchAuditEvents(ctx context.Context, filter AuditFilter) ([]model.AuditEvent, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.PVZID != "" {
+		if uuid.Validate(filter.PVZID) != nil {
+			return []model.AuditEvent{}, nil
+		}
+		add("pvz_id = $%d", filter.PVZID)
+	}
+
+	if filter.ActorID != "" {
+		add("actor_id = $%d", filter.ActorID)
+	}
+
+	if filter.From != nil {
+		add("occurred_at >= $%d", *filter.From)
+	}
+
+	if filter.To != nil {
+		add("occurred_at <= $%d", *filter.To)
+	}
+
+	query := `SELECT id, occurred_at, actor_id, actor_role, action, pvz_id,
+		reception_id, product_id, before, after, request_id, reason
+		FROM audit_events`
+
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	query += fmt.Sprintf(" ORDER BY seq DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	events := []model.AuditEvent{}
+
+	for rows.Next() {
+		var (
+			ev                     model.AuditEvent
+			receptionID, productID sql.NullString
+			before, after          []byte
+		)
+
+		err := rows.Scan(
+			&ev.ID,
+			&ev.OccurredAt,
+			&ev.ActorID,
+			&ev.ActorRole,
+			&ev.Action,
+			&ev.PVZID,
+			&receptionID,
+			&productID,
+			&before,
+			&after,
+			&ev.RequestID,
+			&ev.Reason,
+		)
+
+		if err != nil {
+			return nil, ErrDatabase
+		}
+
+		ev.ReceptionID = receptionID.String
+		ev.ProductID = productID.String
+		ev.Before = before
+		ev.After = after
+
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return events, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullJSON(b json.RawMessage) any {
+	if b == nil {
+		return nil
+	}
+	return []byte(b)
+}
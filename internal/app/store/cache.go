@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// catalogueCacheTTL bounds how long a catalogue change made through another
+// server instance takes to reach this one. Changes made here drop the cache
+// at once.
+const catalogueCacheTTL = 30 * time.Second
+
+// errCatalogueCacheStale reports that a catalogue cache let through a value
+// the database no longer accepts.
+var errCatalogueCacheStale = errors.New("catalogue cache is stale")
+
+// catalogueCache keeps a reference table in memory, so mutations reject
+// unknown values without a query. Whatever it lets through is checked again
+// by the database, see errCatalogueCacheStale.
+type catalogueCache[K comparable, V any] struct {
+	load func(ctx context.Context, db *sql.DB) (map[K]V, error)
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	entries  map[K]V
+}
+
+// all returns the cached table, loading it if needed. Callers must not
+// modify the map.
+func (c *catalogueCache[K, V]) all(ctx context.Context, db *sql.DB) (map[K]V, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil || time.Since(c.loadedAt) > catalogueCacheTTL {
+		entries, err := c.load(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		c.entries = entries
+		c.loadedAt = time.Now()
+	}
+
+	return c.entries, nil
+}
+
+func (c *catalogueCache[K, V]) invalidate() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// resolveCity maps the name or alias of an active city to its current name.
+func (s *Store) resolveCity(ctx context.Context, city model.City) (model.City, error) {
+	names, err := s.cities.all(ctx, s.db)
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := names[city]
+	if !ok {
+		return "", ErrCityNotAllowed
+	}
+
+	return name, nil
+}
+
+// loadActiveCityNames maps the names and aliases of active cities to their
+// current name.
+func loadActiveCityNames(ctx context.Context, db *sql.DB) (map[model.City]model.City, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT c.name, c.name FROM cities c WHERE c.active
+		UNION ALL
+		SELECT a.alias, c.name FROM city_aliases a JOIN cities c ON c.id = a.city_id WHERE c.active`,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	names := make(map[model.City]model.City)
+
+	for rows.Next() {
+		var alias, name model.City
+
+		if err := rows.Scan(&alias, &name); err != nil {
+			return nil, ErrDatabase
+		}
+
+		names[alias] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return names, nil
+}
+
+// ListCities returns the catalogue ordered by name, with inactive cities
+// only if asked for.
+func (s *Store) ListCities(ctx context.Context, includeInactive bool) ([]model.CityEntry, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+cityColumns+`
+		FROM cities c
+		WHERE c.active OR $1
+		ORDER BY c.name`,
+		includeInactive,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	cities := []model.CityEntry{}
+
+	for rows.Next() {
+		city, err := scanCity(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		cities = append(cities, *city)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return cities, nil
+}
+
+// AddCity adds an active city. The name must not be taken by another city,
+// current or former.
+func (s *Store) AddCity(ctx context.Context, name model.City) (*model.CityEntry, error) {
+	tx, err := s.beginCityTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	if err := ensureCityNameFree(ctx, tx, name, ""); err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO cities (id, name, active, created_at) VALUES ($1, $2, TRUE, $3)`,
+		id,
+		name,
+		time.Now(),
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return s.commitCity(ctx, tx, id)
+}
+
+// RenameCity renames a city in the catalogue and in every PVZ. The old name
+// becomes an alias; renaming a city back to one of its aliases drops it.
+func (s *Store) RenameCity(ctx context.Context, id string, name model.City) (*model.CityEntry, error) {
+	tx, err := s.beginCityTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	current, err := getCity(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Name == name {
+		return current, nil
+	}
+
+	if err := ensureCityNameFree(ctx, tx, name, id); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM city_aliases WHERE alias = $1`, name)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	// pvz.city follows through ON UPDATE CASCADE.
+	_, err = tx.ExecContext(ctx, `UPDATE cities SET name = $2 WHERE id = $1`, id, name)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO city_aliases (alias, city_id) VALUES ($1, $2)`,
+		current.Name,
+		id,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return s.commitCity(ctx, tx, id)
+}
+
+// SetCityActive activates or deactivates a city. Existing PVZs of an
+// inactive city are kept, new ones cannot be created there.
+func (s *Store) SetCityActive(ctx context.Context, id string, active bool) (*model.CityEntry, error) {
+	tx, err := s.beginCityTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := getCity(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE cities SET active = $2 WHERE id = $1`, id, active)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return s.commitCity(ctx, tx, id)
+}
+
+// beginCityTx serializes catalogue changes, so a name cannot be taken by
+// two cities at once. The lock does not block reads or PVZ inserts.
+func (s *Store) beginCityTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE cities IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		tx.Rollback()
+		return nil, ErrDatabase
+	}
+
+	return tx, nil
+}
+
+func (s *Store) commitCity(ctx context.Context, tx *sql.Tx, id string) (*model.CityEntry, error) {
+	city, err := getCity(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	s.cities.invalidate()
+
+	return city, nil
+}
+
+// ensureCityNameFree fails if name is the name or an alias of a city other
+// than exceptID.
+func ensureCityNameFree(ctx context.Context, tx *sql.Tx, name model.City, exceptID string) error {
+	var taken bool
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM cities WHERE name = $1)
+			OR EXISTS (SELECT 1 FROM city_aliases WHERE alias = $1 AND city_id::text <> $2)`,
+		name,
+		exceptID,
+	).Scan(&taken)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if taken {
+		return ErrCityAlreadyExists
+	}
+
+	return nil
+}
+
+const cityColumns = `c.id, c.name, c.active, c.created_at,
+	ARRAY(SELECT a.alias FROM city_aliases a WHERE a.city_id = c.id ORDER BY a.alias)`
+
+func getCity(ctx context.Context, tx *sql.Tx, id string) (*model.CityEntry, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrCityNotFound
+	}
+
+	city, err := scanCity(tx.QueryRowContext(ctx, `SELECT `+cityColumns+` FROM cities c WHERE c.id = $1`, id))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCityNotFound
+	}
+
+	return city, err
+}
+
+func scanCity(row scanner) (*model.CityEntry, error) {
+	var (
+		city    model.CityEntry
+		aliases []string
+	)
+
+	err := row.Scan(&city.ID, &city.Name, &city.Active, &city.CreatedAt, pq.Array(&aliases))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	city.Aliases = make([]model.City, len(aliases))
+	for i, a := range aliases {
+		city.Aliases[i] = model.City(a)
+	}
+
+	return &city, nil
+}
@@ -0,0 +1,27 @@
+package store_test
+
+import (
+	"database/sql"
+	"os"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/storetest"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreContract(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	storetest.Run(t, func(t *testing.T) store.Repository {
+		return store.New(db)
+	})
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PVZCursor points at the last PVZ of a page in (registration_date, id)
+// order. Clients only ever see its opaque encoded form.
+type PVZCursor struct {
+	RegistrationDate time.Time `json:"d"`
+	ID               string    `json:"i"`
+}
+
+func (c PVZCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodePVZCursor(s string) (*PVZCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c PVZCursor
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.RegistrationDate.IsZero() || uuid.Validate(c.ID) != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
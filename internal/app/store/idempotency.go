@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotencyKey identifies a request a client may retry. Keys are scoped
+// to the user, and Fingerprint tells a retry from a different request
+// reusing the key. A reservation without a response is held until
+// LockedUntil; after that the request is presumed lost and the key can be
+// reclaimed.
+type IdempotencyKey struct {
+	UserID      string
+	Key         string
+	Fingerprint string
+	ExpiresAt   time.Time
+	LockedUntil time.Time
+}
+
+// StoredResponse is the first response to an idempotent request, replayed
+// to its retries.
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// ReserveIdempotencyKey claims key for a new request and returns nil, or
+// returns the stored response of an earlier request with the same key.
+// Expired keys of the user are dropped first, so a key can be reused once
+// its TTL has passed. A reservation whose lease has run out without a
+// response is taken over.
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key IdempotencyKey) (*StoredResponse, error) {
+	now := time.Now()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND expires_at <= $2`,
+		key.UserID,
+		now,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.status IS NULL AND idempotency_keys.locked_until <= $4`,
+		key.UserID,
+		key.Key,
+		key.Fingerprint,
+		now,
+		key.ExpiresAt,
+		key.LockedUntil,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, ErrDatabase
+	} else if n == 1 {
+		return nil, nil
+	}
+
+	var (
+		fingerprint string
+		status      sql.NullInt64
+		resp        StoredResponse
+	)
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`SELECT fingerprint, status, content_type, body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2`,
+		key.UserID,
+		key.Key,
+	).Scan(&fingerprint, &status, &resp.ContentType, &resp.Body)
+
+	// The request holding the key failed and released it in between.
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if fingerprint != key.Fingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+
+	if !status.Valid {
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	resp.Status = int(status.Int64)
+
+	return &resp, nil
+}
+
+// SaveIdempotentResponse stores the response to the request holding key.
+// If the key was taken over after its lease ran out, the first response
+// saved is kept.
+func (s *Store) SaveIdempotentResponse(ctx context.Context, userID, key string, resp StoredResponse) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE idempotency_keys SET status = $3, content_type = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND status IS NULL`,
+		userID,
+		key,
+		resp.Status,
+		resp.ContentType,
+		resp.Body,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key whose request produced no response
+// worth replaying, so a retry runs the request again.
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND status IS NULL`,
+		userID,
+		key,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
@@ -15,17 +15,179 @@ type ReceptionCreator interface {
 }
 
 type ProductAdder interface {
-	AddProduct(ctx context.Context, pvzID string, prodType model.ProductType) (*model.Product, error)
+	AddProduct(ctx context.Context, pvzID string, draft ProductDraft) (*model.Product, error)
+}
+
+type ProductBatchAdder interface {
+	AddProducts(ctx context.Context, pvzID string, drafts []ProductDraft) ([]model.Product, error)
+}
+
+// ProductFinder looks products up by barcode, newest first and at most
+// ProductLookupLimit of them.
+type ProductFinder interface {
+	FindProductsByBarcode(ctx context.Context, barcode string) ([]model.Product, error)
 }
 
 type ProductDeleter interface {
 	DeleteLastProduct(ctx context.Context, pvzID string) error
 }
 
+// ProductRemover deletes any product of an open reception, not only the
+// last one. ProductPVZ tells which PVZ a product belongs to, so the caller
+// can check access before deleting it.
+type ProductRemover interface {
+	ProductPVZ(ctx context.Context, productID string) (string, error)
+	DeleteProduct(ctx context.Context, pvzID, productID, reason string) (*model.Product, error)
+}
+
 type ReceptionCloser interface {
 	CloseLastReception(ctx context.Context, pvzID string) (*model.Reception, error)
 }
 
 type PVZFetcher interface {
 	FetchPVZList(ctx context.Context, startDate, endDate *time.Time, page, limit int) ([]*model.PVZWithReceptions, error)
+	FetchPVZListAfter(ctx context.Context, startDate, endDate *time.Time, after *PVZCursor, limit int) ([]*model.PVZWithReceptions, *PVZCursor, error)
+}
+
+type UserCreator interface {
+	CreateUser(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error)
+}
+
+type UserFetcher interface {
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+}
+
+type PVZGetter interface {
+	FetchPVZ(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error)
+}
+
+type RefreshTokenCreator interface {
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+}
+
+// RefreshTokenRotator exchanges a refresh token for next, which inherits the
+// user and family of the exchanged token and the user's current role.
+// Presenting an already rotated token revokes the whole family and returns
+// ErrRefreshTokenReused.
+type RefreshTokenRotator interface {
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *model.RefreshToken) error
+}
+
+type TokenRevoker interface {
+	RevokeRefreshTokenFamily(ctx context.Context, userID, tokenHash string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+type AccessTokenChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type PVZAssigner interface {
+	AssignPVZ(ctx context.Context, userID, pvzID string) error
+	UnassignPVZ(ctx context.Context, userID, pvzID string) error
+	ListAssignedPVZs(ctx context.Context, userID string) ([]model.PVZ, error)
+}
+
+type AssignmentChecker interface {
+	IsAssigned(ctx context.Context, userID, pvzID string) (bool, error)
+}
+
+type AuditFetcher interface {
+	FetchAuditEvents(ctx context.Context, filter AuditFilter) ([]model.AuditEvent, error)
+}
+
+// EventOutbox hands pending events to the relay. A claimed event is hidden
+// from other claims until its lease runs out, so an event whose outcome was
+// never recorded is delivered again. SequenceEvents makes committed events
+// visible to PVZEventReader.
+type EventOutbox interface {
+	SequenceEvents(ctx context.Context) error
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]model.Event, error)
+	MarkEventPublished(ctx context.Context, id string) error
+	MarkEventFailed(ctx context.Context, id, reason string, retryAt time.Time) error
+}
+
+// PVZEventReader reads the events of a PVZ for live streams. Seq numbers
+// grow in the order events are committed and only committed events are
+// returned, so a stream resumes after the last seq it saw without skipping
+// any. An event is returned once EventOutbox.SequenceEvents has run after
+// its commit.
+type PVZEventReader interface {
+	LastPVZEventSeq(ctx context.Context, pvzID string) (int64, error)
+	FetchPVZEvents(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error)
+}
+
+// IdempotencyStore remembers responses to requests sent with an
+// Idempotency-Key, so retries get the first response instead of repeating
+// the change.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key IdempotencyKey) (*StoredResponse, error)
+	SaveIdempotentResponse(ctx context.Context, userID, key string, resp StoredResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+}
+
+type CityManager interface {
+	ListCities(ctx context.Context, includeInactive bool) ([]model.CityEntry, error)
+	AddCity(ctx context.Context, name model.City) (*model.CityEntry, error)
+	RenameCity(ctx context.Context, id string, name model.City) (*model.CityEntry, error)
+	SetCityActive(ctx context.Context, id string, active bool) (*model.CityEntry, error)
+}
+
+type ProductTypeManager interface {
+	ListProductTypes(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error)
+	AddProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error)
+	UpdateProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error)
+	SetProductTypeActive(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error)
+}
+
+type WebhookManager interface {
+	CreateWebhook(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]model.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, filter DeliveryFilter) ([]model.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error)
+}
+
+type WebhookDeliveryEnqueuer interface {
+	EnqueueWebhookDeliveries(ctx context.Context, ev model.Event) error
+}
+
+// WebhookDeliveryQueue hands due deliveries to the webhook worker. Like
+// EventOutbox, a claimed delivery is hidden until its lease runs out.
+type WebhookDeliveryQueue interface {
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DeliveryJob, error)
+	CompleteWebhookDelivery(ctx context.Context, id string, statusCode int) error
+	FailWebhookDelivery(ctx context.Context, id string, failure DeliveryFailure) error
+}
+
+// Repository is the full set of operations the server needs from a store
+// backend. Both the Postgres Store and the in-memory store implement it.
+type Repository interface {
+	PVZCreator
+	ReceptionCreator
+	ProductAdder
+	ProductBatchAdder
+	ProductFinder
+	ProductDeleter
+	ProductRemover
+	ReceptionCloser
+	PVZFetcher
+	PVZGetter
+	UserCreator
+	UserFetcher
+	RefreshTokenCreator
+	RefreshTokenRotator
+	TokenRevoker
+	AccessTokenChecker
+	PVZAssigner
+	AssignmentChecker
+	AuditFetcher
+	EventOutbox
+	PVZEventReader
+	IdempotencyStore
+	CityManager
+	ProductTypeManager
+	WebhookManager
+	WebhookDeliveryEnqueuer
+	WebhookDeliveryQueue
 }
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/memory"
+	"pvz_server/internal/app/store/storetest"
+	"testing"
+)
+
+func TestStoreContract(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Repository {
+		return memory.New()
+	})
+}
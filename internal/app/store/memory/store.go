@@ -0,0 +1,1521 @@
+// Package memory implements store.Repository in process memory. It follows
+// the same business rules and returns the same sentinel errors as the
+// Postgres store, so the API can be run and tested without a database.
+package memory
+
+import (
+	"context"
+	"maps"
+	"pvz_server/internal/app/metrics"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ store.Repository = (*Store)(nil)
+
+type Store struct {
+	mu sync.RWMutex
+
+	pvzs map[string]model.PVZ
+	// receptions of every PVZ in creation order.
+	receptions map[string][]*model.Reception
+	// products of every reception in creation order.
+	products map[string][]model.Product
+	users    map[string]model.User
+	// refreshTokens by token hash.
+	refreshTokens map[string]*refreshToken
+	// revokedAccess maps revoked access token IDs to their expiry.
+	revokedAccess map[string]time.Time
+	// assignments maps employees to the set of their PVZs.
+	assignments map[string]map[string]bool
+	// events is the audit log in the order it was written.
+	events []model.AuditEvent
+	// outbox holds every event in the order it was written, published ones
+	// are kept for event streams.
+	outbox []*outboxEntry
+	// webhooks and deliveries in creation order.
+	webhooks   []model.WebhookSubscription
+	deliveries []*model.WebhookDelivery
+	// idempotency keys by user and key.
+	idempotency map[idempotencyID]*idempotencyEntry
+	// cities is the city catalogue in creation order.
+	cities []*model.CityEntry
+	// productTypes is the product type catalogue in creation order.
+	productTypes []*model.ProductTypeEntry
+}
+
+type idempotencyID struct {
+	userID string
+	key    string
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	expiresAt   time.Time
+	lockedUntil time.Time
+	// resp is nil while the first request is being handled.
+	resp *store.StoredResponse
+}
+
+type outboxEntry struct {
+	model.Event
+	availableAt time.Time
+	published   bool
+}
+
+type refreshToken struct {
+	model.RefreshToken
+	used    bool
+	revoked bool
+}
+
+func New() *Store {
+	s := &Store{
+		pvzs:       make(map[string]model.PVZ),
+		receptions: make(map[string][]*model.Reception),
+		products:   make(map[string][]model.Product),
+		users:      make(map[string]model.User),
+
+		refreshTokens: make(map[string]*refreshToken),
+		revokedAccess: make(map[string]time.Time),
+		assignments:   make(map[string]map[string]bool),
+		idempotency:   make(map[idempotencyID]*idempotencyEntry),
+	}
+
+	for _, name := range model.DefaultCities {
+		s.cities = append(s.cities, &model.CityEntry{
+			ID:        uuid.NewString(),
+			Name:      name,
+			Aliases:   []model.City{},
+			Active:    true,
+			CreatedAt: now(),
+		})
+	}
+
+	for _, pt := range model.DefaultProductTypes {
+		pt.Names = maps.Clone(pt.Names)
+		pt.Active = true
+		pt.CreatedAt = now()
+		s.productTypes = append(s.productTypes, &pt)
+	}
+
+	return s
+}
+
+func (s *Store) CreatePVZ(ctx context.Context, city model.City) (*model.PVZ, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cityByName(city)
+	if c == nil || !c.Active {
+		return nil, store.ErrCityNotAllowed
+	}
+
+	pvz := model.PVZ{
+		ID:               uuid.NewString(),
+		RegistrationDate: now(),
+		City:             c.Name,
+	}
+
+	ev, err := store.NewAuditEvent(ctx, store.AuditRecord{
+		Action: model.AuditPVZCreated,
+		PVZID:  pvz.ID,
+		After:  pvz,
+	}, pvz.RegistrationDate)
+
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	s.pvzs[pvz.ID] = pvz
+	s.events = append(s.events, *ev)
+
+	metrics.PVZCreatedTotal.Inc()
+
+	return &pvz, nil
+}
+
+func (s *Store) CreateReception(ctx context.Context, pvzID string) (*model.Reception, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	if s.activeReception(pvzID) != nil {
+		return nil, store.ErrReceptionAlreadyExists
+	}
+
+	r := &model.Reception{
+		ID:       uuid.NewString(),
+		DateTime: now(),
+		PvzID:    pvzID,
+		Status:   model.InProgress,
+	}
+
+	ev, err := store.NewAuditEvent(ctx, store.AuditRecord{
+		Action:      model.AuditReceptionOpened,
+		PVZID:       pvzID,
+		ReceptionID: r.ID,
+		After:       r,
+	}, r.DateTime)
+
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	out, err := store.NewEvent(model.EventReceptionOpened, pvzID, r, r.DateTime)
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	s.receptions[pvzID] = append(s.receptions[pvzID], r)
+	s.events = append(s.events, *ev)
+	s.enqueue(out)
+
+	metrics.ReceptionsCreatedTotal.Inc()
+
+	result := *r
+	return &result, nil
+}
+
+func (s *Store) AddProduct(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+	products, err := s.AddProducts(ctx, pvzID, []store.ProductDraft{draft})
+	if err != nil {
+		return nil, err
+	}
+
+	return &products[0], nil
+}
+
+func (s *Store) AddProducts(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := store.ValidateProductDrafts(drafts, func(t model.ProductType) bool {
+		pt := s.productType(t)
+		return pt != nil && pt.Active
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	r := s.activeReception(pvzID)
+	if r == nil {
+		return nil, store.ErrNoActiveReception
+	}
+
+	for _, d := range drafts {
+		if d.ID != "" && s.productExists(d.ID) {
+			return nil, store.ErrProductAlreadyExists
+		}
+	}
+
+	for _, d := range drafts {
+		if d.Barcode != "" && s.barcodeExists(r.ID, d.Barcode) {
+			return nil, store.ErrBarcodeAlreadyExists
+		}
+	}
+
+	products := store.NewProducts(drafts, r.ID, now())
+
+	var (
+		events []model.AuditEvent
+		outbox []*model.Event
+	)
+
+	for _, p := range products {
+		ev, err := store.NewAuditEvent(ctx, store.AuditRecord{
+			Action:      model.AuditProductAdded,
+			PVZID:       pvzID,
+			ReceptionID: r.ID,
+			ProductID:   p.ID,
+			After:       p,
+		}, p.DateTime)
+
+		if err != nil {
+			return nil, store.ErrDatabase
+		}
+
+		out, err := store.NewEvent(model.EventProductAdded, pvzID, p, p.DateTime)
+		if err != nil {
+			return nil, store.ErrDatabase
+		}
+
+		events = append(events, *ev)
+		outbox = append(outbox, out)
+	}
+
+	s.products[r.ID] = append(s.products[r.ID], products...)
+	s.events = append(s.events, events...)
+
+	for _, out := range outbox {
+		s.enqueue(out)
+	}
+
+	for _, p := range products {
+		metrics.ProductsAddedTotal.WithLabelValues(string(p.Type)).Inc()
+	}
+
+	return products, nil
+}
+
+// The caller must hold s.mu.
+func (s *Store) productExists(id string) bool {
+	for _, products := range s.products {
+		for _, p := range products {
+			if p.ID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// The caller must hold s.mu.
+func (s *Store) barcodeExists(receptionID, barcode string) bool {
+	for _, p := range s.products[receptionID] {
+		if p.Barcode == barcode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) FindProductsByBarcode(ctx context.Context, barcode string) ([]model.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	products := []model.Product{}
+
+	for _, receptionProducts := range s.products {
+		for _, p := range receptionProducts {
+			if p.Barcode == barcode {
+				products = append(products, p)
+			}
+		}
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].DateTime.Equal(products[j].DateTime) {
+			return products[i].DateTime.After(products[j].DateTime)
+		}
+		return products[i].ID < products[j].ID
+	})
+
+	if len(products) > store.ProductLookupLimit {
+		products = products[:store.ProductLookupLimit]
+	}
+
+	return products, nil
+}
+
+func (s *Store) DeleteLastProduct(ctx context.Context, pvzID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return store.ErrPVZNotFound
+	}
+
+	r := s.activeReception(pvzID)
+	if r == nil {
+		return store.ErrNoActiveReception
+	}
+
+	products := s.products[r.ID]
+	if len(products) == 0 {
+		return store.ErrNoProductsToDelete
+	}
+
+	_, err := s.deleteProduct(ctx, pvzID, r.ID, len(products)-1, "")
+
+	return err
+}
+
+func (s *Store) ProductPVZ(ctx context.Context, productID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for pvzID, receptions := range s.receptions {
+		for _, r := range receptions {
+			if slices.ContainsFunc(s.products[r.ID], func(p model.Product) bool { return p.ID == productID }) {
+				return pvzID, nil
+			}
+		}
+	}
+
+	return "", store.ErrProductNotFound
+}
+
+func (s *Store) DeleteProduct(ctx context.Context, pvzID, productID, reason string) (*model.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	for _, r := range s.receptions[pvzID] {
+		idx := slices.IndexFunc(s.products[r.ID], func(p model.Product) bool { return p.ID == productID })
+		if idx < 0 {
+			continue
+		}
+
+		if r.Status != model.InProgress {
+			return nil, store.ErrProductReceptionClosed
+		}
+
+		return s.deleteProduct(ctx, pvzID, r.ID, idx, reason)
+	}
+
+	return nil, store.ErrProductNotFound
+}
+
+// deleteProduct removes the product at idx of the reception and records it.
+// The caller must hold s.mu.
+func (s *Store) deleteProduct(ctx context.Context, pvzID, receptionID string, idx int, reason string) (*model.Product, error) {
+	products := s.products[receptionID]
+	product := products[idx]
+	at := now()
+
+	ev, err := store.NewAuditEvent(ctx, store.AuditRecord{
+		Action:      model.AuditProductDeleted,
+		PVZID:       pvzID,
+		ReceptionID: receptionID,
+		ProductID:   product.ID,
+		Before:      product,
+		Reason:      reason,
+	}, at)
+
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	out, err := store.NewEvent(model.EventProductDeleted, pvzID, product, at)
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	s.products[receptionID] = slices.Delete(slices.Clone(products), idx, idx+1)
+	s.events = append(s.events, *ev)
+	s.enqueue(out)
+
+	metrics.ProductsDeletedTotal.WithLabelValues(string(product.Type)).Inc()
+
+	return &product, nil
+}
+
+func (s *Store) CloseLastReception(ctx context.Context, pvzID string) (*model.Reception, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	r := s.activeReception(pvzID)
+	if r == nil {
+		return nil, store.ErrNoActiveReception
+	}
+
+	closed := *r
+	closed.Status = model.Closed
+	at := now()
+
+	ev, err := store.NewAuditEvent(ctx, store.AuditRecord{
+		Action:      model.AuditReceptionClosed,
+		PVZID:       pvzID,
+		ReceptionID: r.ID,
+		Before:      r,
+		After:       closed,
+	}, at)
+
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	out, err := store.NewEvent(model.EventReceptionClosed, pvzID, closed, at)
+	if err != nil {
+		return nil, store.ErrDatabase
+	}
+
+	r.Status = model.Closed
+	s.events = append(s.events, *ev)
+	s.enqueue(out)
+
+	metrics.ReceptionsClosedTotal.Inc()
+
+	return &closed, nil
+}
+
+func (s *Store) FetchPVZList(ctx context.Context, startDate, endDate *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pvzs := s.matchingPVZs(startDate, endDate, nil)
+	offset := (page - 1) * limit
+
+	if offset >= len(pvzs) {
+		pvzs = nil
+	} else {
+		pvzs = pvzs[offset:min(offset+limit, len(pvzs))]
+	}
+
+	return s.withReceptions(pvzs, startDate, endDate), nil
+}
+
+func (s *Store) FetchPVZListAfter(ctx context.Context, startDate, endDate *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pvzs := s.matchingPVZs(startDate, endDate, after)
+
+	var next *store.PVZCursor
+
+	if len(pvzs) > limit {
+		pvzs = pvzs[:limit]
+		last := pvzs[len(pvzs)-1]
+		next = &store.PVZCursor{RegistrationDate: last.RegistrationDate, ID: last.ID}
+	}
+
+	return s.withReceptions(pvzs, startDate, endDate), next, nil
+}
+
+func (s *Store) FetchPVZ(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pvz, ok := s.pvzs[pvzID]
+	if !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	return s.withReceptions([]model.PVZ{pvz}, nil, nil)[0], nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+	if !model.AllowedUserRoles[role] {
+		return nil, store.ErrRoleNotAllowed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[email]; ok {
+		return nil, store.ErrUserAlreadyExists
+	}
+
+	u := model.User{
+		ID:           uuid.NewString(),
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+
+	s.users[email] = u
+
+	return &u, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[email]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+
+	return &u, nil
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if token.FamilyID == "" {
+		token.FamilyID = uuid.NewString()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(token.UserID) {
+		return store.ErrUserNotFound
+	}
+
+	for hash, t := range s.refreshTokens {
+		if !t.ExpiresAt.After(time.Now()) {
+			delete(s.refreshTokens, hash)
+		}
+	}
+
+	s.insertRefreshToken(token)
+
+	return nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, tokenHash string, next *model.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return store.ErrRefreshTokenInvalid
+	}
+
+	if old.used {
+		s.revokeFamily(old.FamilyID)
+		return store.ErrRefreshTokenReused
+	}
+
+	if old.revoked || !old.ExpiresAt.After(time.Now()) {
+		return store.ErrRefreshTokenInvalid
+	}
+
+	// The role may have changed since login, the new pair carries the
+	// current one.
+	user, ok := s.userByID(old.UserID)
+	if !ok {
+		return store.ErrRefreshTokenInvalid
+	}
+
+	old.used = true
+
+	next.FamilyID = old.FamilyID
+	next.UserID = old.UserID
+	next.Role = user.Role
+
+	s.insertRefreshToken(next)
+
+	return nil
+}
+
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, userID, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok || token.UserID != userID {
+		return store.ErrRefreshTokenInvalid
+	}
+
+	s.revokeFamily(token.FamilyID)
+
+	return nil
+}
+
+func (s *Store) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneRevokedAccess()
+	s.revokedAccess[jti] = expiresAt
+
+	return nil
+}
+
+func (s *Store) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.revokedAccess[jti]
+	return ok, nil
+}
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key store.IdempotencyKey) (*store.StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyID{userID: key.UserID, key: key.Key}
+
+	e, ok := s.idempotency[id]
+	if !ok || !e.expiresAt.After(now()) || (e.resp == nil && !e.lockedUntil.After(now())) {
+		s.idempotency[id] = &idempotencyEntry{
+			fingerprint: key.Fingerprint,
+			expiresAt:   key.ExpiresAt,
+			lockedUntil: key.LockedUntil,
+		}
+		return nil, nil
+	}
+
+	if e.fingerprint != key.Fingerprint {
+		return nil, store.ErrIdempotencyKeyMismatch
+	}
+
+	if e.resp == nil {
+		return nil, store.ErrIdempotencyKeyInUse
+	}
+
+	resp := *e.resp
+	resp.Body = slices.Clone(e.resp.Body)
+
+	return &resp, nil
+}
+
+func (s *Store) SaveIdempotentResponse(ctx context.Context, userID, key string, resp store.StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.idempotency[idempotencyID{userID: userID, key: key}]; ok && e.resp == nil {
+		resp.Body = slices.Clone(resp.Body)
+		e.resp = &resp
+	}
+
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyID{userID: userID, key: key}
+
+	if e, ok := s.idempotency[id]; ok && e.resp == nil {
+		delete(s.idempotency, id)
+	}
+
+	return nil
+}
+
+func (s *Store) ListCities(ctx context.Context, includeInactive bool) ([]model.CityEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cities := []model.CityEntry{}
+
+	for _, c := range s.cities {
+		if c.Active || includeInactive {
+			cities = append(cities, copyCity(c))
+		}
+	}
+
+	sort.Slice(cities, func(i, j int) bool {
+		return cities[i].Name < cities[j].Name
+	})
+
+	return cities, nil
+}
+
+func (s *Store) AddCity(ctx context.Context, name model.City) (*model.CityEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cityByName(name) != nil {
+		return nil, store.ErrCityAlreadyExists
+	}
+
+	c := &model.CityEntry{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Aliases:   []model.City{},
+		Active:    true,
+		CreatedAt: now(),
+	}
+
+	s.cities = append(s.cities, c)
+
+	city := copyCity(c)
+	return &city, nil
+}
+
+func (s *Store) RenameCity(ctx context.Context, id string, name model.City) (*model.CityEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cityByID(id)
+	if c == nil {
+		return nil, store.ErrCityNotFound
+	}
+
+	if c.Name != name {
+		if other := s.cityByName(name); other != nil && other != c {
+			return nil, store.ErrCityAlreadyExists
+		}
+
+		aliases := slices.DeleteFunc(slices.Clone(c.Aliases), func(a model.City) bool {
+			return a == name
+		})
+		aliases = append(aliases, c.Name)
+		slices.Sort(aliases)
+
+		for id, pvz := range s.pvzs {
+			if pvz.City == c.Name {
+				pvz.City = name
+				s.pvzs[id] = pvz
+			}
+		}
+
+		c.Name = name
+		c.Aliases = aliases
+	}
+
+	city := copyCity(c)
+	return &city, nil
+}
+
+func (s *Store) SetCityActive(ctx context.Context, id string, active bool) (*model.CityEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cityByID(id)
+	if c == nil {
+		return nil, store.ErrCityNotFound
+	}
+
+	c.Active = active
+
+	city := copyCity(c)
+	return &city, nil
+}
+
+// cityByName finds the city with name as its name or alias.
+// The caller must hold s.mu.
+func (s *Store) cityByName(name model.City) *model.CityEntry {
+	for _, c := range s.cities {
+		if c.Name == name || slices.Contains(c.Aliases, name) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// The caller must hold s.mu.
+func (s *Store) cityByID(id string) *model.CityEntry {
+	for _, c := range s.cities {
+		if c.ID == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func copyCity(c *model.CityEntry) model.CityEntry {
+	city := *c
+	city.Aliases = slices.Clone(c.Aliases)
+	return city
+}
+
+func (s *Store) ListProductTypes(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	types := []model.ProductTypeEntry{}
+
+	for _, pt := range s.productTypes {
+		if pt.Active || includeInactive {
+			types = append(types, copyProductType(pt))
+		}
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Code < types[j].Code
+	})
+
+	return types, nil
+}
+
+func (s *Store) AddProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.productType(pt.Code) != nil {
+		return nil, store.ErrProductTypeAlreadyExists
+	}
+
+	pt.Active = true
+	pt.CreatedAt = now()
+
+	stored := copyProductType(&pt)
+	s.productTypes = append(s.productTypes, &stored)
+
+	created := copyProductType(&stored)
+	return &created, nil
+}
+
+func (s *Store) UpdateProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.productType(pt.Code)
+	if stored == nil {
+		return nil, store.ErrProductTypeNotFound
+	}
+
+	stored.Names = maps.Clone(pt.Names)
+	stored.Fragile = pt.Fragile
+	stored.MaxWeightGrams = nil
+	if pt.MaxWeightGrams != nil {
+		w := *pt.MaxWeightGrams
+		stored.MaxWeightGrams = &w
+	}
+
+	updated := copyProductType(stored)
+	return &updated, nil
+}
+
+func (s *Store) SetProductTypeActive(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.productType(code)
+	if stored == nil {
+		return nil, store.ErrProductTypeNotFound
+	}
+
+	stored.Active = active
+
+	updated := copyProductType(stored)
+	return &updated, nil
+}
+
+// The caller must hold s.mu.
+func (s *Store) productType(code model.ProductType) *model.ProductTypeEntry {
+	for _, pt := range s.productTypes {
+		if pt.Code == code {
+			return pt
+		}
+	}
+
+	return nil
+}
+
+func copyProductType(pt *model.ProductTypeEntry) model.ProductTypeEntry {
+	c := *pt
+	c.Names = maps.Clone(pt.Names)
+	if pt.MaxWeightGrams != nil {
+		w := *pt.MaxWeightGrams
+		c.MaxWeightGrams = &w
+	}
+	return c
+}
+
+func (s *Store) AssignPVZ(ctx context.Context, userID, pvzID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.userByID(userID)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+
+	if u.Role != model.Employee {
+		return store.ErrUserNotEmployee
+	}
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return store.ErrPVZNotFound
+	}
+
+	if s.assignments[userID] == nil {
+		s.assignments[userID] = make(map[string]bool)
+	}
+
+	s.assignments[userID][pvzID] = true
+
+	return nil
+}
+
+func (s *Store) UnassignPVZ(ctx context.Context, userID, pvzID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.assignments[userID][pvzID] {
+		return store.ErrAssignmentNotFound
+	}
+
+	delete(s.assignments[userID], pvzID)
+
+	return nil
+}
+
+func (s *Store) ListAssignedPVZs(ctx context.Context, userID string) ([]model.PVZ, error) {
+	if uuid.Validate(userID) != nil {
+		return nil, store.ErrUserNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pvzs := []model.PVZ{}
+
+	for pvzID := range s.assignments[userID] {
+		pvzs = append(pvzs, s.pvzs[pvzID])
+	}
+
+	sort.Slice(pvzs, func(i, j int) bool {
+		return pvzBefore(pvzs[i], pvzs[j])
+	})
+
+	return pvzs, nil
+}
+
+func (s *Store) IsAssigned(ctx context.Context, userID, pvzID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return false, store.ErrPVZNotFound
+	}
+
+	return s.assignments[userID][pvzID], nil
+}
+
+func (s *Store) FetchAuditEvents(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []model.AuditEvent{}
+	offset := (filter.Page - 1) * filter.Limit
+
+	for i := len(s.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		ev := s.events[i]
+
+		if !auditMatches(ev, filter) {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		events = append(events, ev)
+	}
+
+	return events, nil
+}
+
+// SequenceEvents does nothing, events get their seq under the lock as they
+// are written.
+func (s *Store) SequenceEvents(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]model.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := time.Now()
+	events := []model.Event{}
+
+	for _, e := range s.outbox {
+		if len(events) == limit {
+			break
+		}
+
+		if e.published || e.availableAt.After(t) {
+			continue
+		}
+
+		e.availableAt = t.Add(lease)
+		events = append(events, e.Event)
+	}
+
+	return events, nil
+}
+
+func (s *Store) MarkEventPublished(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.outbox {
+		if e.ID == id {
+			e.published = true
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) MarkEventFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.outbox {
+		if e.ID == id && !e.published {
+			e.Attempts++
+			e.availableAt = retryAt
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) LastPVZEventSeq(ctx context.Context, pvzID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return 0, store.ErrPVZNotFound
+	}
+
+	for i := len(s.outbox) - 1; i >= 0; i-- {
+		if s.outbox[i].PVZID == pvzID {
+			return s.outbox[i].Seq, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (s *Store) FetchPVZEvents(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.pvzs[pvzID]; !ok {
+		return nil, store.ErrPVZNotFound
+	}
+
+	events := []model.Event{}
+
+	// Seq is the position in s.outbox, so the scan starts right after afterSeq.
+	for _, e := range s.outbox[min(max(afterSeq, 0), int64(len(s.outbox))):] {
+		if len(events) == limit {
+			break
+		}
+
+		if e.PVZID == pvzID {
+			events = append(events, e.Event)
+		}
+	}
+
+	return events, nil
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error) {
+	eventTypes, err := store.ValidateEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	w := model.WebhookSubscription{
+		ID:         uuid.NewString(),
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  now(),
+	}
+
+	s.mu.Lock()
+	s.webhooks = append(s.webhooks, w)
+	s.mu.Unlock()
+
+	return &w, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]model.WebhookSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := make([]model.WebhookSubscription, len(s.webhooks))
+	copy(webhooks, s.webhooks)
+
+	return webhooks, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.webhookIndex(id)
+	if i < 0 {
+		return store.ErrWebhookNotFound
+	}
+
+	s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+
+	deliveries := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if d.WebhookID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	s.deliveries = deliveries
+
+	return nil
+}
+
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, ev model.Event) error {
+	payload, err := store.DeliveryPayload(ev)
+	if err != nil {
+		return store.ErrDatabase
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+
+	for _, w := range s.webhooks {
+		if !slices.Contains(w.EventTypes, ev.Type) || s.hasDelivery(w.ID, ev.ID) {
+			continue
+		}
+
+		s.deliveries = append(s.deliveries, &model.WebhookDelivery{
+			ID:            uuid.NewString(),
+			WebhookID:     w.ID,
+			EventID:       ev.ID,
+			EventType:     ev.Type,
+			Payload:       payload,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: t,
+			CreatedAt:     t,
+			UpdatedAt:     t,
+		})
+	}
+
+	return nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]store.DeliveryJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+
+	var due []*model.WebhookDelivery
+
+	for _, d := range s.deliveries {
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(t) {
+			due = append(due, d)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	jobs := []store.DeliveryJob{}
+
+	for _, d := range due[:min(limit, len(due))] {
+		d.NextAttemptAt = t.Add(lease)
+		w := s.webhooks[s.webhookIndex(d.WebhookID)]
+
+		jobs = append(jobs, store.DeliveryJob{Delivery: *d, URL: w.URL, Secret: w.Secret})
+	}
+
+	return jobs, nil
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id string, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.pendingDelivery(id); d != nil {
+		d.Status = model.DeliverySucceeded
+		d.Attempts++
+		d.LastStatusCode = statusCode
+		d.LastError = ""
+		d.UpdatedAt = now()
+	}
+
+	return nil
+}
+
+func (s *Store) FailWebhookDelivery(ctx context.Context, id string, failure store.DeliveryFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.pendingDelivery(id); d != nil {
+		if failure.Dead {
+			d.Status = model.DeliveryDead
+		}
+
+		d.Attempts++
+		// Kept at the precision of now(), so a retry due now is claimable
+		// right away.
+		d.NextAttemptAt = failure.RetryAt.UTC().Truncate(time.Microsecond)
+		d.LastStatusCode = failure.StatusCode
+		d.LastError = failure.Error
+		d.UpdatedAt = now()
+	}
+
+	return nil
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, filter store.DeliveryFilter) ([]model.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.webhookIndex(webhookID) < 0 {
+		return nil, store.ErrWebhookNotFound
+	}
+
+	deliveries := []model.WebhookDelivery{}
+	offset := (filter.Page - 1) * filter.Limit
+
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < filter.Limit; i-- {
+		d := s.deliveries[i]
+
+		if d.WebhookID != webhookID || (filter.Status != "" && d.Status != filter.Status) {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Store) ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.webhookIndex(webhookID) < 0 {
+		return nil, store.ErrWebhookNotFound
+	}
+
+	for _, d := range s.deliveries {
+		if d.ID != deliveryID || d.WebhookID != webhookID {
+			continue
+		}
+
+		if d.Status != model.DeliveryDead {
+			return nil, store.ErrDeliveryNotDead
+		}
+
+		t := now()
+		d.Status = model.DeliveryPending
+		d.Attempts = 0
+		d.NextAttemptAt = t
+		d.UpdatedAt = t
+
+		result := *d
+		return &result, nil
+	}
+
+	return nil, store.ErrDeliveryNotFound
+}
+
+// The caller must hold s.mu.
+func (s *Store) webhookIndex(id string) int {
+	for i, w := range s.webhooks {
+		if w.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// The caller must hold s.mu.
+func (s *Store) hasDelivery(webhookID, eventID string) bool {
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID && d.EventID == eventID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The caller must hold s.mu.
+func (s *Store) pendingDelivery(id string) *model.WebhookDelivery {
+	for _, d := range s.deliveries {
+		if d.ID == id && d.Status == model.DeliveryPending {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// The caller must hold s.mu.
+func (s *Store) enqueue(ev *model.Event) {
+	ev.Seq = int64(len(s.outbox) + 1)
+	s.outbox = append(s.outbox, &outboxEntry{Event: *ev, availableAt: ev.OccurredAt})
+}
+
+// The caller must hold s.mu.
+func (s *Store) userExists(userID string) bool {
+	_, ok := s.userByID(userID)
+	return ok
+}
+
+// The caller must hold s.mu.
+func (s *Store) userByID(userID string) (model.User, bool) {
+	for _, u := range s.users {
+		if u.ID == userID {
+			return u, true
+		}
+	}
+
+	return model.User{}, false
+}
+
+// The caller must hold s.mu.
+func (s *Store) insertRefreshToken(token *model.RefreshToken) {
+	token.ID = uuid.NewString()
+	token.CreatedAt = now()
+
+	s.refreshTokens[token.TokenHash] = &refreshToken{RefreshToken: *token}
+}
+
+// pruneRevokedAccess drops revocations of access tokens that have expired.
+// The caller must hold s.mu.
+func (s *Store) pruneRevokedAccess() {
+	for jti, expiresAt := range s.revokedAccess {
+		if !expiresAt.After(time.Now()) {
+			delete(s.revokedAccess, jti)
+		}
+	}
+}
+
+// revokeFamily revokes every refresh token of the family and the access
+// tokens issued with them. The caller must hold s.mu.
+func (s *Store) revokeFamily(familyID string) {
+	s.pruneRevokedAccess()
+
+	for _, t := range s.refreshTokens {
+		if t.FamilyID != familyID {
+			continue
+		}
+
+		t.revoked = true
+
+		if t.AccessExpiresAt.After(time.Now()) {
+			s.revokedAccess[t.AccessJTI] = t.AccessExpiresAt
+		}
+	}
+}
+
+// activeReception returns the in_progress reception of the PVZ or nil.
+// The caller must hold s.mu.
+func (s *Store) activeReception(pvzID string) *model.Reception {
+	receptions := s.receptions[pvzID]
+
+	for i := len(receptions) - 1; i >= 0; i-- {
+		if receptions[i].Status == model.InProgress {
+			return receptions[i]
+		}
+	}
+
+	return nil
+}
+
+// matchingPVZs mirrors the Postgres query: without a date range every PVZ
+// matches, otherwise only PVZs with a reception inside the range. The result
+// is ordered by (registration date, ID) and starts strictly after the cursor.
+// The caller must hold s.mu.
+func (s *Store) matchingPVZs(startDate, endDate *time.Time, after *store.PVZCursor) []model.PVZ {
+	var result []model.PVZ
+
+	for _, pvz := range s.pvzs {
+		if after != nil && !pvzAfter(pvz, after) {
+			continue
+		}
+
+		if (startDate != nil || endDate != nil) && len(s.receptionsInRange(pvz.ID, startDate, endDate)) == 0 {
+			continue
+		}
+
+		result = append(result, pvz)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return pvzBefore(result[i], result[j])
+	})
+
+	return result
+}
+
+// The caller must hold s.mu.
+func (s *Store) receptionsInRange(pvzID string, startDate, endDate *time.Time) []model.Reception {
+	var result []model.Reception
+
+	for _, r := range s.receptions[pvzID] {
+		if startDate != nil && r.DateTime.Before(*startDate) {
+			continue
+		}
+
+		if endDate != nil && r.DateTime.After(*endDate) {
+			continue
+		}
+
+		result = append(result, *r)
+	}
+
+	return result
+}
+
+// The caller must hold s.mu.
+func (s *Store) withReceptions(pvzs []model.PVZ, startDate, endDate *time.Time) []*model.PVZWithReceptions {
+	result := make([]*model.PVZWithReceptions, 0, len(pvzs))
+
+	for _, pvz := range pvzs {
+		item := &model.PVZWithReceptions{
+			PVZ:        pvz,
+			Receptions: []model.ReceptionWithProducts{},
+		}
+
+		for _, r := range s.receptionsInRange(pvz.ID, startDate, endDate) {
+			products := make([]model.Product, len(s.products[r.ID]))
+			copy(products, s.products[r.ID])
+
+			item.Receptions = append(item.Receptions, model.ReceptionWithProducts{
+				Reception: r,
+				Products:  products,
+			})
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+func auditMatches(ev model.AuditEvent, f store.AuditFilter) bool {
+	switch {
+	case f.PVZID != "" && ev.PVZID != f.PVZID:
+		return false
+	case f.ActorID != "" && ev.ActorID != f.ActorID:
+		return false
+	case f.From != nil && ev.OccurredAt.Before(*f.From):
+		return false
+	case f.To != nil && ev.OccurredAt.After(*f.To):
+		return false
+	}
+	return true
+}
+
+func pvzBefore(a, b model.PVZ) bool {
+	if !a.RegistrationDate.Equal(b.RegistrationDate) {
+		return a.RegistrationDate.Before(b.RegistrationDate)
+	}
+	return a.ID < b.ID
+}
+
+func pvzAfter(pvz model.PVZ, c *store.PVZCursor) bool {
+	if !pvz.RegistrationDate.Equal(c.RegistrationDate) {
+		return pvz.RegistrationDate.After(c.RegistrationDate)
+	}
+	return pvz.ID > c.ID
+}
+
+// now matches the microsecond precision of Postgres timestamps so both
+// backends produce identical cursors.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"pvz_server/internal/app/model"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewEvent builds an outbox event carrying data as its payload.
+func NewEvent(eventType model.EventType, pvzID string, data any, now time.Time) (*model.Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		PVZID:      pvzID,
+		OccurredAt: now,
+		Data:       payload,
+	}, nil
+}
+
+// enqueueEvent writes an event to the outbox inside tx, so it is published
+// if and only if the change it describes is committed.
+func enqueueEvent(ctx context.Context, tx *sql.Tx, eventType model.EventType, pvzID string, data any, now time.Time) error {
+	ev, err := NewEvent(eventType, pvzID, data, now)
+	if err != nil {
+		return ErrDatabase
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO outbox (id, event_type, pvz_id, data, occurred_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`,
+		ev.ID,
+		ev.Type,
+		ev.PVZID,
+		[]byte(ev.Data),
+		ev.OccurredAt,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// ClaimEvents returns up to limit unpublished events in the order they were
+// written and hides them from other claims for the lease. SKIP LOCKED lets
+// several relays share the outbox without handing out the same event twice.
+func (s *Store) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]model.Event, error) {
+	now := time.Now()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`UPDATE outbox SET available_at = $1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE published_at IS NULL AND available_at <= $2
+			ORDER BY seq
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING seq, id, event_type, pvz_id, data, occurred_at, attempts`,
+		now.Add(lease),
+		now,
+		limit,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery.
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Seq < events[j].Seq
+	})
+
+	return events, nil
+}
+
+// streamSeqLock is the advisory lock key that serialises SequenceEvents.
+const streamSeqLock = 5_108_437_221
+
+// SequenceEvents numbers committed events for streams. Outbox seq is taken
+// at insert, so a transaction that commits late can add an event below one
+// a reader has already seen. stream_seq is assigned only to committed
+// events, by one run at a time under an advisory lock, so the numbers become
+// visible in increasing order and a reader resuming after the highest one it
+// saw skips nothing. If another relay holds the lock, it covers the caller.
+func (s *Store) SequenceEvents(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	var locked bool
+
+	err = tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, streamSeqLock).Scan(&locked)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if !locked {
+		return nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`WITH base AS (
+			SELECT COALESCE(MAX(stream_seq), 0) AS seq FROM outbox
+		), pending AS (
+			SELECT seq, ROW_NUMBER() OVER (ORDER BY seq) AS n
+			FROM outbox
+			WHERE stream_seq IS NULL
+		)
+		UPDATE outbox o SET stream_seq = base.seq + pending.n
+		FROM base, pending
+		WHERE o.seq = pending.seq`,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// LastPVZEventSeq returns the stream seq of the latest sequenced event of
+// the PVZ, or 0 if it has none.
+func (s *Store) LastPVZEventSeq(ctx context.Context, pvzID string) (int64, error) {
+	if uuid.Validate(pvzID) != nil {
+		return 0, ErrPVZNotFound
+	}
+
+	var (
+		exists bool
+		seq    sql.NullInt64
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM pvz WHERE id = $1),
+			(SELECT MAX(stream_seq) FROM outbox WHERE pvz_id = $1)`,
+		pvzID,
+	).Scan(&exists, &seq)
+
+	if err != nil {
+		return 0, ErrDatabase
+	}
+
+	if !exists {
+		return 0, ErrPVZNotFound
+	}
+
+	return seq.Int64, nil
+}
+
+// FetchPVZEvents returns up to limit sequenced events of the PVZ after
+// afterSeq, oldest first. The Seq of the returned events is their stream
+// seq. Events wait for the relay to sequence them, so reads take no locks.
+func (s *Store) FetchPVZEvents(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+	if uuid.Validate(pvzID) != nil {
+		return nil, ErrPVZNotFound
+	}
+
+	var exists bool
+
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pvz WHERE id = $1)`, pvzID).Scan(&exists)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if !exists {
+		return nil, ErrPVZNotFound
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT stream_seq, id, event_type, pvz_id, data, occurred_at, attempts
+		FROM outbox
+		WHERE pvz_id = $1 AND stream_seq > $2
+		ORDER BY stream_seq
+		LIMIT $3`,
+		pvzID,
+		afterSeq,
+		limit,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]model.Event, error) {
+	events := []model.Event{}
+
+	for rows.Next() {
+		var (
+			ev   model.Event
+			data []byte
+		)
+
+		err := rows.Scan(&ev.Seq, &ev.ID, &ev.Type, &ev.PVZID, &data, &ev.OccurredAt, &ev.Attempts)
+		if err != nil {
+			return nil, ErrDatabase
+		}
+
+		ev.Data = data
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return events, nil
+}
+
+func (s *Store) MarkEventPublished(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE outbox SET published_at = $1, last_error = NULL
+		WHERE id = $2`,
+		time.Now(),
+		id,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) MarkEventFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE outbox SET attempts = attempts + 1, last_error = $1, available_at = $2
+		WHERE id = $3 AND published_at IS NULL`,
+		reason,
+		retryAt,
+		id,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductDraft is a product to be added. An empty ID is generated, a
+// client-chosen one lets the caller match created products to its items.
+// Barcode and OrderID are optional.
+type ProductDraft struct {
+	ID      string
+	Type    model.ProductType
+	Barcode string
+	OrderID string
+}
+
+// ProductTypeError lists the drafts, by index, whose type is not an active
+// type of the catalogue. It matches ErrProductTypeNotAllowed.
+type ProductTypeError struct {
+	Indexes []int
+}
+
+func (e *ProductTypeError) Error() string {
+	return ErrProductTypeNotAllowed.Error()
+}
+
+func (e *ProductTypeError) Is(target error) bool {
+	return target == ErrProductTypeNotAllowed
+}
+
+// ValidateProductDrafts rejects types that are not allowed, malformed or
+// repeated IDs and repeated barcodes.
+func ValidateProductDrafts(drafts []ProductDraft, allowed func(model.ProductType) bool) error {
+	var typeErr ProductTypeError
+
+	for i, d := range drafts {
+		if !allowed(d.Type) {
+			typeErr.Indexes = append(typeErr.Indexes, i)
+		}
+	}
+
+	if len(typeErr.Indexes) > 0 {
+		return &typeErr
+	}
+
+	ids := make(map[string]bool, len(drafts))
+
+	for _, d := range drafts {
+		if d.ID == "" {
+			continue
+		}
+
+		if uuid.Validate(d.ID) != nil {
+			return ErrInvalidProductID
+		}
+
+		if ids[d.ID] {
+			return ErrProductAlreadyExists
+		}
+
+		ids[d.ID] = true
+	}
+
+	barcodes := make(map[string]bool, len(drafts))
+
+	for _, d := range drafts {
+		if d.Barcode == "" {
+			continue
+		}
+
+		if barcodes[d.Barcode] {
+			return ErrBarcodeAlreadyExists
+		}
+
+		barcodes[d.Barcode] = true
+	}
+
+	return nil
+}
+
+// NewProducts builds the products for drafts. Each product is a microsecond
+// later than the previous one, so the batch keeps its order by date_time
+// and delete_last_product removes its last item first.
+func NewProducts(drafts []ProductDraft, receptionID string, now time.Time) []model.Product {
+	products := make([]model.Product, len(drafts))
+
+	for i, d := range drafts {
+		id := d.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+
+		products[i] = model.Product{
+			ID:          id,
+			DateTime:    now.Add(time.Duration(i) * time.Microsecond),
+			Type:        d.Type,
+			ReceptionID: receptionID,
+			Barcode:     d.Barcode,
+			OrderID:     d.OrderID,
+		}
+	}
+
+	return products
+}
+
+// ProductLookupLimit caps the products returned by a barcode lookup. A
+// barcode is unique only within a reception, so it may match products of
+// many receptions; the newest come first.
+const ProductLookupLimit = 100
+
+const productColumns = `id, date_time, type, reception_id, COALESCE(barcode, ''), COALESCE(order_id, '')`
+
+func (s *Store) FindProductsByBarcode(ctx context.Context, barcode string) ([]model.Product, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+productColumns+` FROM product
+		WHERE barcode = $1
+		ORDER BY date_time DESC, id
+		LIMIT $2`,
+		barcode,
+		ProductLookupLimit,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	products := []model.Product{}
+
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, ErrDatabase
+		}
+
+		products = append(products, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return products, nil
+}
+
+// scanProduct reads productColumns and returns scan errors as is.
+func scanProduct(row scanner) (*model.Product, error) {
+	var p model.Product
+
+	err := row.Scan(&p.ID, &p.DateTime, &p.Type, &p.ReceptionID, &p.Barcode, &p.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// loadActiveProductTypes returns the codes of active product types.
+func loadActiveProductTypes(ctx context.Context, db *sql.DB) (map[model.ProductType]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT code FROM product_types WHERE active`)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	types := make(map[model.ProductType]bool)
+
+	for rows.Next() {
+		var code model.ProductType
+
+		if err := rows.Scan(&code); err != nil {
+			return nil, ErrDatabase
+		}
+
+		types[code] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return types, nil
+}
+
+// ensureProductTypesActive checks the types of drafts inside tx, so a type
+// deactivated since the cache was loaded is never used.
+func ensureProductTypesActive(ctx context.Context, tx *sql.Tx, drafts []ProductDraft) error {
+	seen := make(map[model.ProductType]bool)
+	var codes []string
+
+	for _, d := range drafts {
+		if !seen[d.Type] {
+			seen[d.Type] = true
+			codes = append(codes, string(d.Type))
+		}
+	}
+
+	var active int
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM product_types WHERE code = ANY($1) AND active`,
+		pq.Array(codes),
+	).Scan(&active)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if active != len(codes) {
+		return errCatalogueCacheStale
+	}
+
+	return nil
+}
+
+// ListProductTypes returns the catalogue ordered by code, with inactive
+// types only if asked for.
+func (s *Store) ListProductTypes(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+productTypeColumns+`
+		FROM product_types
+		WHERE active OR $1
+		ORDER BY code`,
+		includeInactive,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	types := []model.ProductTypeEntry{}
+
+	for rows.Next() {
+		pt, err := scanProductType(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		types = append(types, *pt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return types, nil
+}
+
+// AddProductType adds an active product type.
+func (s *Store) AddProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	names, err := json.Marshal(pt.Names)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO product_types (code, names, fragile, max_weight_grams, active, created_at)
+		VALUES ($1, $2, $3, $4, TRUE, $5)
+		RETURNING `+productTypeColumns,
+		pt.Code,
+		names,
+		pt.Fragile,
+		nullInt(pt.MaxWeightGrams),
+		time.Now(),
+	)
+
+	created, err := scanProductType(row)
+
+	if isUniqueViolation(err) {
+		return nil, ErrProductTypeAlreadyExists
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	s.productTypes.invalidate()
+
+	return created, nil
+}
+
+// UpdateProductType replaces the names and attributes of the type with
+// pt.Code. The code itself never changes, products keep referring to it.
+func (s *Store) UpdateProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	names, err := json.Marshal(pt.Names)
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`UPDATE product_types SET names = $2, fragile = $3, max_weight_grams = $4
+		WHERE code = $1
+		RETURNING `+productTypeColumns,
+		pt.Code,
+		names,
+		pt.Fragile,
+		nullInt(pt.MaxWeightGrams),
+	)
+
+	return scanUpdatedProductType(row)
+}
+
+// SetProductTypeActive activates or deactivates a product type. Products
+// already added keep their type, new ones cannot use an inactive one.
+func (s *Store) SetProductTypeActive(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error) {
+	row := s.db.QueryRowContext(
+		ctx,
+		`UPDATE product_types SET active = $2
+		WHERE code = $1
+		RETURNING `+productTypeColumns,
+		code,
+		active,
+	)
+
+	pt, err := scanUpdatedProductType(row)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productTypes.invalidate()
+
+	return pt, nil
+}
+
+const productTypeColumns = `code, names, fragile, max_weight_grams, active, created_at`
+
+func scanUpdatedProductType(row *sql.Row) (*model.ProductTypeEntry, error) {
+	pt, err := scanProductType(row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductTypeNotFound
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return pt, nil
+}
+
+// scanProductType returns scan errors as is, callers map them.
+func scanProductType(row scanner) (*model.ProductTypeEntry, error) {
+	var (
+		pt        model.ProductTypeEntry
+		names     []byte
+		maxWeight sql.NullInt64
+	)
+
+	err := row.Scan(&pt.Code, &names, &pt.Fragile, &maxWeight, &pt.Active, &pt.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(names, &pt.Names); err != nil {
+		return nil, err
+	}
+
+	if maxWeight.Valid {
+		w := int(maxWeight.Int64)
+		pt.MaxWeightGrams = &w
+	}
+
+	return &pt, nil
+}
+
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
@@ -1,90 +1,214 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/lib/pq"
 )
 
-func aggregatePVZResults(rows *sql.Rows) ([]*model.PVZWithReceptions, error) {
-	type pvzKey = string
+// receptionRow is a single row of the reception × product join. Product is
+// nil for receptions without products.
+type receptionRow struct {
+	Reception model.Reception
+	Product   *model.Product
+}
+
+// queryPVZs selects a page of distinct PVZs ordered by (registration_date, id).
+// Pages are cut over PVZs rather than joined rows, so a PVZ is never split
+// between pages no matter how many receptions and products it has. When
+// after is set, only PVZs strictly past the cursor are returned.
+func (s *Store) queryPVZs(ctx context.Context, startDate, endDate *time.Time, after *PVZCursor, offset, limit int) ([]model.PVZ, error) {
+	var afterDate *time.Time
+	var afterID *string
+
+	if after != nil {
+		afterDate = &after.RegistrationDate
+		afterID = &after.ID
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT p.id, p.registration_date, p.city
+		FROM pvz p
+		WHERE (
+				($1::timestamp IS NULL AND $2::timestamp IS NULL)
+				OR EXISTS (
+					SELECT 1 FROM reception r
+					WHERE r.pvz_id = p.id
+					  AND ($1::timestamp IS NULL OR r.date_time >= $1)
+					  AND ($2::timestamp IS NULL OR r.date_time <= $2)
+				)
+			)
+		  AND ($3::timestamp IS NULL OR (p.registration_date, p.id) > ($3::timestamp, $4::uuid))
+		ORDER BY p.registration_date, p.id
+		OFFSET $5 LIMIT $6`,
+		startDate,
+		endDate,
+		afterDate,
+		afterID,
+		offset,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPVZs(rows)
+}
+
+func (s *Store) withReceptions(ctx context.Context, pvzs []model.PVZ, startDate, endDate *time.Time) ([]*model.PVZWithReceptions, error) {
+	receptions, err := s.loadReceptions(ctx, pvzIDs(pvzs), startDate, endDate)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return assemblePVZPage(pvzs, receptions), nil
+}
+
+func scanPVZs(rows *sql.Rows) ([]model.PVZ, error) {
+	defer rows.Close()
+
+	var pvzs []model.PVZ
+
+	for rows.Next() {
+		var p model.PVZ
+
+		if err := rows.Scan(&p.ID, &p.RegistrationDate, &p.City); err != nil {
+			return nil, err
+		}
+
+		pvzs = append(pvzs, p)
+	}
+
+	return pvzs, rows.Err()
+}
+
+func pvzIDs(pvzs []model.PVZ) []string {
+	ids := make([]string, 0, len(pvzs))
+
+	for _, p := range pvzs {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
+
+// loadReceptions returns every reception of the given PVZs that falls into
+// the date range, joined with all of its products.
+func (s *Store) loadReceptions(ctx context.Context, pvzIDs []string, startDate, endDate *time.Time) ([]receptionRow, error) {
+	if len(pvzIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT r.id, r.date_time, r.pvz_id, r.status,
+		       pr.id, pr.date_time, pr.type, pr.barcode, pr.order_id
+		FROM reception r
+		LEFT JOIN product pr ON pr.reception_id = r.id
+		WHERE r.pvz_id = ANY($1::uuid[])
+		  AND ($2::timestamp IS NULL OR r.date_time >= $2)
+		  AND ($3::timestamp IS NULL OR r.date_time <= $3)
+		ORDER BY r.date_time, r.id, pr.date_time, pr.id`,
+		pq.Array(pvzIDs),
+		startDate,
+		endDate,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
 
-	pvzMap := make(map[pvzKey]*model.PVZWithReceptions)
+	var result []receptionRow
 
 	for rows.Next() {
 		var (
-			pvzID, receptionID                  string
-			productID                           sql.NullString
-			pvzDate, receptionDate, productDate sql.NullTime
-			pvzCity                             model.City
-			receptionStatus                     model.ReceptionStatus
-			productType                         sql.NullString
+			row         receptionRow
+			productID   sql.NullString
+			productDate sql.NullTime
+			productType sql.NullString
+			barcode     sql.NullString
+			orderID     sql.NullString
 		)
 
 		err := rows.Scan(
-			&pvzID,
-			&pvzDate,
-			&pvzCity,
-			&receptionID,
-			&receptionDate,
-			&receptionStatus,
+			&row.Reception.ID,
+			&row.Reception.DateTime,
+			&row.Reception.PvzID,
+			&row.Reception.Status,
 			&productID,
 			&productDate,
 			&productType,
+			&barcode,
+			&orderID,
 		)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if _, exists := pvzMap[pvzID]; !exists {
-			pvzMap[pvzID] = &model.PVZWithReceptions{
-				PVZ: model.PVZ{
-					ID:               pvzID,
-					RegistrationDate: pvzDate.Time,
-					City:             pvzCity,
-				},
+		if productID.Valid {
+			row.Product = &model.Product{
+				ID:          productID.String,
+				DateTime:    productDate.Time,
+				Type:        model.ProductType(productType.String),
+				ReceptionID: row.Reception.ID,
+				Barcode:     barcode.String,
+				OrderID:     orderID.String,
 			}
 		}
 
-		if receptionID == "" {
-			continue
-		}
+		result = append(result, row)
+	}
 
-		pvz := pvzMap[pvzID]
-		var currentReception *model.ReceptionWithProducts
-		for i := range pvz.Receptions {
-			if pvz.Receptions[i].Reception.ID == receptionID {
-				currentReception = &pvz.Receptions[i]
-				break
-			}
+	return result, rows.Err()
+}
+
+// assemblePVZPage groups reception rows under their PVZs. The order of pvzs
+// is kept as is, receptions and products keep the order of rows.
+func assemblePVZPage(pvzs []model.PVZ, rows []receptionRow) []*model.PVZWithReceptions {
+	result := make([]*model.PVZWithReceptions, 0, len(pvzs))
+	byID := make(map[string]*model.PVZWithReceptions, len(pvzs))
+
+	for _, p := range pvzs {
+		item := &model.PVZWithReceptions{
+			PVZ:        p,
+			Receptions: []model.ReceptionWithProducts{},
 		}
 
-		if currentReception == nil {
-			currentReception = &model.ReceptionWithProducts{
-				Reception: model.Reception{
-					ID:       receptionID,
-					DateTime: receptionDate.Time,
-					PvzID:    pvzID,
-					Status:   receptionStatus,
-				},
-			}
-			pvz.Receptions = append(pvz.Receptions, *currentReception)
-			currentReception = &pvz.Receptions[len(pvz.Receptions)-1]
+		result = append(result, item)
+		byID[p.ID] = item
+	}
+
+	receptionIdx := make(map[string]int)
+
+	for _, row := range rows {
+		pvz, ok := byID[row.Reception.PvzID]
+		if !ok {
+			continue
 		}
 
-		if productID.Valid && productType.Valid {
-			currentReception.Products = append(currentReception.Products, model.Product{
-				ID:          productID.String,
-				DateTime:    productDate.Time,
-				Type:        model.ProductType(productType.String),
-				ReceptionID: receptionID,
+		idx, ok := receptionIdx[row.Reception.ID]
+		if !ok {
+			pvz.Receptions = append(pvz.Receptions, model.ReceptionWithProducts{
+				Reception: row.Reception,
+				Products:  []model.Product{},
 			})
+			idx = len(pvz.Receptions) - 1
+			receptionIdx[row.Reception.ID] = idx
 		}
-	}
 
-	var result []*model.PVZWithReceptions
-	for _, pvz := range pvzMap {
-		result = append(result, pvz)
+		if row.Product != nil {
+			pvz.Receptions[idx].Products = append(pvz.Receptions[idx].Products, *row.Product)
+		}
 	}
-	return result, nil
+
+	return result
 }
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"pvz_server/internal/app/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssemblePVZPage_KeepsPVZOrder(t *testing.T) {
+	now := time.Now()
+	pvzs := []model.PVZ{
+		{ID: "pvz-3", RegistrationDate: now},
+		{ID: "pvz-1", RegistrationDate: now.Add(time.Second)},
+		{ID: "pvz-2", RegistrationDate: now.Add(2 * time.Second)},
+	}
+
+	rows := []receptionRow{
+		{Reception: model.Reception{ID: "r-2", PvzID: "pvz-2"}},
+		{Reception: model.Reception{ID: "r-1", PvzID: "pvz-1"}, Product: &model.Product{ID: "p-1"}},
+		{Reception: model.Reception{ID: "r-1", PvzID: "pvz-1"}, Product: &model.Product{ID: "p-2"}},
+	}
+
+	result := assemblePVZPage(pvzs, rows)
+
+	require.Len(t, result, 3)
+	assert.Equal(t, "pvz-3", result[0].PVZ.ID)
+	assert.Equal(t, "pvz-1", result[1].PVZ.ID)
+	assert.Equal(t, "pvz-2", result[2].PVZ.ID)
+
+	assert.NotNil(t, result[0].Receptions)
+	assert.Empty(t, result[0].Receptions)
+
+	require.Len(t, result[1].Receptions, 1)
+	require.Len(t, result[1].Receptions[0].Products, 2)
+	assert.Equal(t, "p-1", result[1].Receptions[0].Products[0].ID)
+	assert.Equal(t, "p-2", result[1].Receptions[0].Products[1].ID)
+
+	require.Len(t, result[2].Receptions, 1)
+	assert.NotNil(t, result[2].Receptions[0].Products)
+	assert.Empty(t, result[2].Receptions[0].Products)
+}
+
+func TestAssemblePVZPage_IgnoresForeignReceptions(t *testing.T) {
+	pvzs := []model.PVZ{{ID: "pvz-1"}}
+	rows := []receptionRow{
+		{Reception: model.Reception{ID: "r-1", PvzID: "pvz-other"}},
+	}
+
+	result := assemblePVZPage(pvzs, rows)
+
+	require.Len(t, result, 1)
+	assert.Empty(t, result[0].Receptions)
+}
+
+func TestPVZCursor_RoundTrip(t *testing.T) {
+	c := PVZCursor{
+		RegistrationDate: time.Date(2025, 4, 13, 10, 0, 0, 123456000, time.UTC),
+		ID:               "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11",
+	}
+
+	decoded, err := DecodePVZCursor(c.Encode())
+
+	require.NoError(t, err)
+	assert.Equal(t, c.ID, decoded.ID)
+	assert.True(t, c.RegistrationDate.Equal(decoded.RegistrationDate))
+}
+
+func TestDecodePVZCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"garbage!", "e30", PVZCursor{RegistrationDate: time.Now(), ID: "x"}.Encode()} {
+		_, err := DecodePVZCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
@@ -2,27 +2,74 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"pvz_server/internal/app/metrics"
 	"pvz_server/internal/app/model"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrCityNotAllowed         = errors.New("unsupported city")
-	ErrDatabase               = errors.New("database error")
-	ErrReceptionAlreadyExists = errors.New("receprion in progress")
-	ErrProductTypeNotAllowed  = errors.New("unsupported product type")
-	ErrNoActiveReception      = errors.New("no active reception for this PVZ")
-	ErrNoProductsToDelete     = errors.New("no products to delete")
+	ErrCityNotAllowed           = errors.New("unsupported city")
+	ErrPVZNotFound              = errors.New("pvz not found")
+	ErrDatabase                 = errors.New("database error")
+	ErrReceptionAlreadyExists   = errors.New("receprion in progress")
+	ErrProductTypeNotAllowed    = errors.New("unsupported product type")
+	ErrNoActiveReception        = errors.New("no active reception for this PVZ")
+	ErrNoProductsToDelete       = errors.New("no products to delete")
+	ErrRoleNotAllowed           = errors.New("unsupported role")
+	ErrUserAlreadyExists        = errors.New("user already exists")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrRefreshTokenInvalid      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reused")
+	ErrUserNotEmployee          = errors.New("user is not an employee")
+	ErrAssignmentNotFound       = errors.New("assignment not found")
+	ErrEventTypeNotAllowed      = errors.New("unsupported event type")
+	ErrWebhookNotFound          = errors.New("webhook not found")
+	ErrDeliveryNotFound         = errors.New("delivery not found")
+	ErrDeliveryNotDead          = errors.New("delivery is not dead")
+	ErrProductAlreadyExists     = errors.New("product already exists")
+	ErrInvalidProductID         = errors.New("invalid product ID")
+	ErrBarcodeAlreadyExists     = errors.New("barcode already exists in reception")
+	ErrProductNotFound          = errors.New("product not found")
+	ErrProductReceptionClosed   = errors.New("product reception is closed")
+	ErrIdempotencyKeyInUse      = errors.New("idempotency key in use")
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key reused with a different request")
+	ErrCityNotFound             = errors.New("city not found")
+	ErrCityAlreadyExists        = errors.New("city already exists")
+	ErrProductTypeNotFound      = errors.New("product type not found")
+	ErrProductTypeAlreadyExists = errors.New("product type already exists")
 )
 
+// CreatePVZ opens a PVZ in an active city of the catalogue. An alias is
+// stored as the current name of its city.
 func (s *Store) CreatePVZ(ctx context.Context, city model.City) (*model.PVZ, error) {
-	if !model.AllowedCities[city] {
+	pvz, err := s.createPVZ(ctx, city)
+
+	// The cache let through a city changed by another instance; decide on
+	// fresh data.
+	if errors.Is(err, errCatalogueCacheStale) {
+		s.cities.invalidate()
+		pvz, err = s.createPVZ(ctx, city)
+	}
+
+	if errors.Is(err, errCatalogueCacheStale) {
 		return nil, ErrCityNotAllowed
 	}
 
+	return pvz, err
+}
+
+func (s *Store) createPVZ(ctx context.Context, city model.City) (*model.PVZ, error) {
+	city, err := s.resolveCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 
 	if err != nil {
@@ -34,10 +81,12 @@ func (s *Store) CreatePVZ(ctx context.Context, city model.City) (*model.PVZ, err
 	id := uuid.NewString()
 	now := time.Now()
 
-	_, err = tx.ExecContext(
+	// The insert checks the city again, so a city deactivated since the
+	// cache was loaded is never used.
+	res, err := tx.ExecContext(
 		ctx,
-		`INSERT INTO pvz (id, registration_date, city) 
-		VALUES ($1, $2, $3)`,
+		`INSERT INTO pvz (id, registration_date, city)
+		SELECT $1, $2, name FROM cities WHERE name = $3 AND active`,
 		id,
 		now,
 		city,
@@ -47,15 +96,35 @@ func (s *Store) CreatePVZ(ctx context.Context, city model.City) (*model.PVZ, err
 		return nil, ErrDatabase
 	}
 
-	if err := tx.Commit(); err != nil {
+	if n, err := res.RowsAffected(); err != nil {
 		return nil, ErrDatabase
+	} else if n == 0 {
+		return nil, errCatalogueCacheStale
 	}
 
-	return &model.PVZ{
+	pvz := &model.PVZ{
 		ID:               id,
 		RegistrationDate: now,
 		City:             city,
-	}, nil
+	}
+
+	err = recordAudit(ctx, tx, AuditRecord{
+		Action: model.AuditPVZCreated,
+		PVZID:  id,
+		After:  pvz,
+	}, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	metrics.PVZCreatedTotal.Inc()
+
+	return pvz, nil
 }
 
 func (s *Store) CreateReception(ctx context.Context, pvzID string) (*model.Reception, error) {
@@ -67,6 +136,10 @@ func (s *Store) CreateReception(ctx context.Context, pvzID string) (*model.Recep
 
 	defer tx.Rollback()
 
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return nil, err
+	}
+
 	var exists bool
 
 	err = tx.QueryRowContext(
@@ -102,27 +175,93 @@ func (s *Store) CreateReception(ctx context.Context, pvzID string) (*model.Recep
 		model.InProgress,
 	)
 
-	if err != nil {
-		return nil, ErrDatabase
+	// A concurrent request may open a reception between the check above and
+	// the insert; the partial unique index on in_progress receptions catches it.
+	if isUniqueViolation(err) {
+		return nil, ErrReceptionAlreadyExists
 	}
 
-	if err := tx.Commit(); err != nil {
+	if isForeignKeyViolation(err) {
+		return nil, ErrPVZNotFound
+	}
+
+	if err != nil {
 		return nil, ErrDatabase
 	}
 
-	return &model.Reception{
+	reception := &model.Reception{
 		ID:       id,
 		DateTime: now,
 		PvzID:    pvzID,
 		Status:   model.InProgress,
-	}, nil
+	}
+
+	err = recordAudit(ctx, tx, AuditRecord{
+		Action:      model.AuditReceptionOpened,
+		PVZID:       pvzID,
+		ReceptionID: id,
+		After:       reception,
+	}, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enqueueEvent(ctx, tx, model.EventReceptionOpened, pvzID, reception, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	metrics.ReceptionsCreatedTotal.Inc()
+
+	return reception, nil
+}
+
+func (s *Store) AddProduct(ctx context.Context, pvzID string, draft ProductDraft) (*model.Product, error) {
+	products, err := s.AddProducts(ctx, pvzID, []ProductDraft{draft})
+	if err != nil {
+		return nil, err
+	}
+
+	return &products[0], nil
 }
 
-func (s *Store) AddProduct(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
-	if !model.AllowedProductTypes[productType] {
+// AddProducts adds every draft to the active reception of the PVZ in one
+// transaction: either all products are added or none.
+func (s *Store) AddProducts(ctx context.Context, pvzID string, drafts []ProductDraft) ([]model.Product, error) {
+	products, err := s.addProducts(ctx, pvzID, drafts)
+
+	// The cache let through a type changed by another instance; decide on
+	// fresh data.
+	if errors.Is(err, errCatalogueCacheStale) {
+		s.productTypes.invalidate()
+		products, err = s.addProducts(ctx, pvzID, drafts)
+	}
+
+	if errors.Is(err, errCatalogueCacheStale) {
 		return nil, ErrProductTypeNotAllowed
 	}
 
+	return products, err
+}
+
+func (s *Store) addProducts(ctx context.Context, pvzID string, drafts []ProductDraft) ([]model.Product, error) {
+	types, err := s.productTypes.all(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateProductDrafts(drafts, func(t model.ProductType) bool {
+		return types[t]
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 
 	if err != nil {
@@ -131,13 +270,21 @@ func (s *Store) AddProduct(ctx context.Context, pvzID string, productType model.
 
 	defer tx.Rollback()
 
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return nil, err
+	}
+
+	// FOR SHARE lets products be added in parallel but waits for a pending
+	// close; once it commits the row no longer matches status = in_progress
+	// and the product is rejected instead of landing in a closed reception.
 	var receptionID string
 	err = tx.QueryRowContext(
 		ctx,
 		`SELECT id FROM reception
 		 WHERE pvz_id = $1 AND status = $2
 		 ORDER BY date_time DESC
-		 LIMIT 1`,
+		 LIMIT 1
+		 FOR SHARE`,
 		pvzID,
 		model.InProgress,
 	).Scan(&receptionID)
@@ -146,33 +293,66 @@ func (s *Store) AddProduct(ctx context.Context, pvzID string, productType model.
 		return nil, ErrNoActiveReception
 	}
 
-	id := uuid.NewString()
-	now := time.Now()
+	if err := ensureProductTypesActive(ctx, tx, drafts); err != nil {
+		return nil, err
+	}
+
+	products := NewProducts(drafts, receptionID, time.Now())
+
+	values := make([]string, len(products))
+	args := make([]any, 0, 6*len(products))
+
+	for i, p := range products {
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", 6*i+1, 6*i+2, 6*i+3, 6*i+4, 6*i+5, 6*i+6)
+		args = append(args, p.ID, p.DateTime, p.Type, p.ReceptionID, nullString(p.Barcode), nullString(p.OrderID))
+	}
 
 	_, err = tx.ExecContext(
 		ctx,
-		`INSERT INTO product (id, date_time, type, reception_id)
-		 VALUES ($1, $2, $3, $4)`,
-		id,
-		now,
-		productType,
-		receptionID,
+		`INSERT INTO product (id, date_time, type, reception_id, barcode, order_id)
+		 VALUES `+strings.Join(values, ", "),
+		args...,
 	)
 
+	if isUniqueViolation(err) && violatedConstraint(err) == "product_reception_barcode_key" {
+		return nil, ErrBarcodeAlreadyExists
+	}
+
+	if isUniqueViolation(err) {
+		return nil, ErrProductAlreadyExists
+	}
+
 	if err != nil {
 		return nil, ErrDatabase
 	}
 
+	for _, p := range products {
+		err = recordAudit(ctx, tx, AuditRecord{
+			Action:      model.AuditProductAdded,
+			PVZID:       pvzID,
+			ReceptionID: receptionID,
+			ProductID:   p.ID,
+			After:       p,
+		}, p.DateTime)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := enqueueEvent(ctx, tx, model.EventProductAdded, pvzID, p, p.DateTime); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, ErrDatabase
 	}
 
-	return &model.Product{
-		ID:          id,
-		DateTime:    now,
-		Type:        productType,
-		ReceptionID: receptionID,
-	}, nil
+	for _, p := range products {
+		metrics.ProductsAddedTotal.WithLabelValues(string(p.Type)).Inc()
+	}
+
+	return products, nil
 }
 
 func (s *Store) DeleteLastProduct(ctx context.Context, pvzID string) error {
@@ -184,43 +364,208 @@ func (s *Store) DeleteLastProduct(ctx context.Context, pvzID string) error {
 
 	defer tx.Rollback()
 
+	receptionID, err := lockActiveReception(ctx, tx, pvzID)
+	if err != nil {
+		return err
+	}
+
+	product, err := scanProduct(tx.QueryRowContext(ctx,
+		`SELECT `+productColumns+` FROM product
+		WHERE reception_id = $1
+		ORDER BY date_time DESC LIMIT 1`,
+		receptionID,
+	))
+
+	if err != nil {
+		return ErrNoProductsToDelete
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM product 
+		WHERE id = $1`,
+		product.ID,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if err := recordProductDeleted(ctx, tx, pvzID, product, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	metrics.ProductsDeletedTotal.WithLabelValues(string(product.Type)).Inc()
+
+	return nil
+}
+
+func (s *Store) ProductPVZ(ctx context.Context, productID string) (string, error) {
+	var pvzID string
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT r.pvz_id FROM product p
+		JOIN reception r ON r.id = p.reception_id
+		WHERE p.id = $1`,
+		productID,
+	).Scan(&pvzID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrProductNotFound
+	}
+
+	if err != nil {
+		return "", ErrDatabase
+	}
+
+	return pvzID, nil
+}
+
+// DeleteProduct deletes a product of the active reception of the PVZ, not
+// necessarily the last one, and records reason in the audit log.
+func (s *Store) DeleteProduct(ctx context.Context, pvzID, productID, reason string) (*model.Product, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	receptionID, err := lockProductReception(ctx, tx, pvzID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := scanProduct(tx.QueryRowContext(ctx,
+		`DELETE FROM product
+		WHERE id = $1 AND reception_id = $2
+		RETURNING `+productColumns,
+		productID,
+		receptionID,
+	))
+
+	// Deleted by a concurrent request while this one waited for the lock.
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if err := recordProductDeleted(ctx, tx, pvzID, product, reason); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	metrics.ProductsDeletedTotal.WithLabelValues(string(product.Type)).Inc()
+
+	return product, nil
+}
+
+// lockActiveReception returns the active reception of the PVZ. FOR UPDATE
+// serializes deletes with each other and with closing the reception, so
+// every product is deleted at most once and never after close.
+func lockActiveReception(ctx context.Context, tx *sql.Tx, pvzID string) (string, error) {
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return "", err
+	}
+
 	var receptionID string
-	err = tx.QueryRowContext(ctx,
+	err := tx.QueryRowContext(ctx,
 		`SELECT id FROM reception
 		WHERE pvz_id = $1 AND status = $2
-		ORDER BY date_time DESC LIMIT 1`,
+		ORDER BY date_time DESC LIMIT 1
+		FOR UPDATE`,
 		pvzID,
 		model.InProgress,
 	).Scan(&receptionID)
 
 	if err != nil {
-		return ErrNoActiveReception
+		return "", ErrNoActiveReception
 	}
 
-	var productID string
+	return receptionID, nil
+}
+
+// lockProductReception returns the reception of a product of the PVZ,
+// locked like in lockActiveReception. A product that is not in the PVZ is
+// not found, one whose reception is closed cannot be deleted. The status is
+// checked again under the lock, since the reception may close in between.
+func lockProductReception(ctx context.Context, tx *sql.Tx, pvzID, productID string) (string, error) {
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return "", err
+	}
+
+	if uuid.Validate(productID) != nil {
+		return "", ErrProductNotFound
+	}
+
+	var (
+		receptionID string
+		status      model.ReceptionStatus
+	)
+
+	err := tx.QueryRowContext(ctx,
+		`SELECT r.id, r.status FROM product p
+		JOIN reception r ON r.id = p.reception_id
+		WHERE p.id = $1 AND r.pvz_id = $2`,
+		productID,
+		pvzID,
+	).Scan(&receptionID, &status)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrProductNotFound
+	}
+
+	if err != nil {
+		return "", ErrDatabase
+	}
+
+	if status != model.InProgress {
+		return "", ErrProductReceptionClosed
+	}
 
 	err = tx.QueryRowContext(ctx,
-		`SELECT id FROM product
-		WHERE reception_id = $1
-		ORDER BY date_time DESC LIMIT 1`,
+		`SELECT status FROM reception WHERE id = $1 FOR UPDATE`,
 		receptionID,
-	).Scan(&productID)
+	).Scan(&status)
 
 	if err != nil {
-		return ErrNoProductsToDelete
+		return "", ErrDatabase
 	}
 
-	_, err = tx.ExecContext(ctx,
-		`DELETE FROM product 
-		WHERE id = $1`,
-		productID,
-	)
+	if status != model.InProgress {
+		return "", ErrProductReceptionClosed
+	}
+
+	return receptionID, nil
+}
+
+func recordProductDeleted(ctx context.Context, tx *sql.Tx, pvzID string, product *model.Product, reason string) error {
+	now := time.Now()
+
+	err := recordAudit(ctx, tx, AuditRecord{
+		Action:      model.AuditProductDeleted,
+		PVZID:       pvzID,
+		ReceptionID: product.ReceptionID,
+		ProductID:   product.ID,
+		Before:      product,
+		Reason:      reason,
+	}, now)
 
 	if err != nil {
-		return ErrDatabase
+		return err
 	}
 
-	return tx.Commit()
+	return enqueueEvent(ctx, tx, model.EventProductDeleted, pvzID, product, now)
 }
 
 func (s *Store) CloseLastReception(ctx context.Context, pvzID string) (*model.Reception, error) {
@@ -232,13 +577,18 @@ func (s *Store) CloseLastReception(ctx context.Context, pvzID string) (*model.Re
 
 	defer tx.Rollback()
 
+	if err := ensurePVZExists(ctx, tx, pvzID); err != nil {
+		return nil, err
+	}
+
 	var r model.Reception
 
 	err = tx.QueryRowContext(
 		ctx,
 		`SELECT id, date_time, status FROM reception
 		 WHERE pvz_id = $1 AND status = $2
-		 ORDER BY date_time DESC LIMIT 1`,
+		 ORDER BY date_time DESC LIMIT 1
+		 FOR UPDATE`,
 		pvzID,
 		model.InProgress,
 	).Scan(
@@ -263,50 +613,131 @@ func (s *Store) CloseLastReception(ctx context.Context, pvzID string) (*model.Re
 		return nil, ErrDatabase
 	}
 
+	r.PvzID = pvzID
+	before := r
+	r.Status = model.Closed
+
+	now := time.Now()
+
+	err = recordAudit(ctx, tx, AuditRecord{
+		Action:      model.AuditReceptionClosed,
+		PVZID:       pvzID,
+		ReceptionID: r.ID,
+		Before:      before,
+		After:       r,
+	}, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enqueueEvent(ctx, tx, model.EventReceptionClosed, pvzID, r, now); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, ErrDatabase
 	}
 
-	r.PvzID = pvzID
-	r.Status = model.Closed
+	metrics.ReceptionsClosedTotal.Inc()
+
 	return &r, nil
 }
 
 func (s *Store) FetchPVZList(ctx context.Context, startDate, endDate *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
 	offset := (page - 1) * limit
 
-	query :=
-		`SELECT p.id, p.registration_date, p.city, 
-		       r.id, r.date_time, r.status,
-		       pr.id, pr.date_time, pr.type
-		FROM pvz p
-		LEFT JOIN reception r ON r.pvz_id = p.id
-		LEFT JOIN product pr ON pr.reception_id = r.id
-		WHERE ($1::timestamp IS NULL OR r.date_time >= $1)
-		  AND ($2::timestamp IS NULL OR r.date_time <= $2)
-		ORDER BY p.registration_date
-		OFFSET $3 LIMIT $4`
-
-	rows, err := s.db.QueryContext(
+	pvzs, err := s.queryPVZs(ctx, startDate, endDate, nil, offset, limit)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return s.withReceptions(ctx, pvzs, startDate, endDate)
+}
+
+func (s *Store) FetchPVZListAfter(ctx context.Context, startDate, endDate *time.Time, after *PVZCursor, limit int) ([]*model.PVZWithReceptions, *PVZCursor, error) {
+	// One extra row tells whether there is a next page without a second query.
+	pvzs, err := s.queryPVZs(ctx, startDate, endDate, after, 0, limit+1)
+
+	if err != nil {
+		return nil, nil, ErrDatabase
+	}
+
+	var next *PVZCursor
+
+	if len(pvzs) > limit {
+		pvzs = pvzs[:limit]
+		last := pvzs[len(pvzs)-1]
+		next = &PVZCursor{RegistrationDate: last.RegistrationDate, ID: last.ID}
+	}
+
+	result, err := s.withReceptions(ctx, pvzs, startDate, endDate)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, next, nil
+}
+
+// ensurePVZExists tells a missing PVZ apart from a PVZ without an active
+// reception, so callers get ErrPVZNotFound instead of a generic error.
+func ensurePVZExists(ctx context.Context, tx *sql.Tx, pvzID string) error {
+	if uuid.Validate(pvzID) != nil {
+		return ErrPVZNotFound
+	}
+
+	var exists bool
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM pvz WHERE id = $1)`,
+		pvzID,
+	).Scan(&exists)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if !exists {
+		return ErrPVZNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) FetchPVZ(ctx context.Context, pvzID string) (*model.PVZWithReceptions, error) {
+	if uuid.Validate(pvzID) != nil {
+		return nil, ErrPVZNotFound
+	}
+
+	var pvz model.PVZ
+
+	err := s.db.QueryRowContext(
 		ctx,
-		query,
-		startDate,
-		endDate,
-		offset,
-		limit,
+		`SELECT id, registration_date, city FROM pvz
+		WHERE id = $1`,
+		pvzID,
+	).Scan(
+		&pvz.ID,
+		&pvz.RegistrationDate,
+		&pvz.City,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPVZNotFound
+	}
+
 	if err != nil {
 		return nil, ErrDatabase
 	}
 
-	defer rows.Close()
-
-	result, err := aggregatePVZResults(rows)
+	receptions, err := s.loadReceptions(ctx, []string{pvz.ID}, nil, nil)
 
 	if err != nil {
 		return nil, ErrDatabase
 	}
 
-	return result, nil
+	return assemblePVZPage([]model.PVZ{pvz}, receptions)[0], nil
 }
@@ -1,15 +1,54 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+	"pvz_server/internal/app/model"
+
+	"github.com/lib/pq"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+var _ Repository = (*Store)(nil)
 
 type Store struct {
-	db *sql.DB
+	db           *sql.DB
+	cities       *catalogueCache[model.City, model.City]
+	productTypes *catalogueCache[model.ProductType, bool]
 }
 
 func New(db *sql.DB) *Store {
-	return &Store{db: db}
+	return &Store{
+		db:           db,
+		cities:       &catalogueCache[model.City, model.City]{load: loadActiveCityNames},
+		productTypes: &catalogueCache[model.ProductType, bool]{load: loadActiveProductTypes},
+	}
 }
 
 func (s *Store) DB() *sql.DB {
 	return s.db
 }
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+// violatedConstraint names the constraint or unique index err violated, if
+// any.
+func violatedConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
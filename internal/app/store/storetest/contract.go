@@ -0,0 +1,1864 @@
+// Package storetest holds the behavioural contract every store.Repository
+// implementation must satisfy. Backends run it from their own tests:
+//
+//	storetest.Run(t, func(t *testing.T) store.Repository { return memory.New() })
+//
+// Tests only rely on data they create themselves, so the suite can run
+// against a shared database.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns a ready to use store for a single test.
+type Factory func(t *testing.T) store.Repository
+
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Repository)
+	}{
+		{"CreatePVZ", testCreatePVZ},
+		{"CreatePVZ_CityNotAllowed", testCreatePVZCityNotAllowed},
+		{"Cities", testCities},
+		{"Cities_Rename", testCitiesRename},
+		{"CreateReception", testCreateReception},
+		{"CreateReception_AlreadyExists", testCreateReceptionAlreadyExists},
+		{"CreateReception_AfterClose", testCreateReceptionAfterClose},
+		{"AddProduct", testAddProduct},
+		{"AddProduct_TypeNotAllowed", testAddProductTypeNotAllowed},
+		{"AddProduct_NoActiveReception", testAddProductNoActiveReception},
+		{"AddProducts", testAddProducts},
+		{"ProductTypes", testProductTypes},
+		{"AddProducts_Atomic", testAddProductsAtomic},
+		{"Products_Barcode", testProductsBarcode},
+		{"DeleteLastProduct_LIFO", testDeleteLastProductLIFO},
+		{"DeleteLastProduct_NoProducts", testDeleteLastProductNoProducts},
+		{"DeleteLastProduct_NoActiveReception", testDeleteLastProductNoActiveReception},
+		{"DeleteProduct", testDeleteProduct},
+		{"DeleteProduct_ClosedReception", testDeleteProductClosedReception},
+		{"CloseLastReception", testCloseLastReception},
+		{"CloseLastReception_NoActiveReception", testCloseLastReceptionNoActiveReception},
+		{"FetchPVZ", testFetchPVZ},
+		{"FetchPVZ_NotFound", testFetchPVZNotFound},
+		{"Mutations_PVZNotFound", testMutationsPVZNotFound},
+		{"FetchPVZList_PageBoundaries", testFetchPVZListPageBoundaries},
+		{"FetchPVZList_DateRange", testFetchPVZListDateRange},
+		{"FetchPVZListAfter", testFetchPVZListAfter},
+		{"Users", testUsers},
+		{"Users_RoleNotAllowed", testUsersRoleNotAllowed},
+		{"RefreshToken_Rotate", testRefreshTokenRotate},
+		{"RefreshToken_RotateCurrentRole", testRefreshTokenRotateCurrentRole},
+		{"RefreshToken_ReuseRevokesFamily", testRefreshTokenReuseRevokesFamily},
+		{"RefreshToken_Expired", testRefreshTokenExpired},
+		{"RefreshToken_RevokeFamily", testRefreshTokenRevokeFamily},
+		{"RefreshToken_UnknownUser", testRefreshTokenUnknownUser},
+		{"AccessToken_Revoke", testAccessTokenRevoke},
+		{"Tokens_Pruned", testTokensPruned},
+		{"Assignments", testAssignments},
+		{"Assignments_Invalid", testAssignmentsInvalid},
+		{"Audit_Mutations", testAuditMutations},
+		{"Audit_Filters", testAuditFilters},
+		{"Outbox_Events", testOutboxEvents},
+		{"Outbox_Retry", testOutboxRetry},
+		{"PVZ_EventStream", testPVZEventStream},
+		{"Idempotency", testIdempotency},
+		{"Idempotency_Expiry", testIdempotencyExpiry},
+		{"Idempotency_Lease", testIdempotencyLease},
+		{"Webhooks", testWebhooks},
+		{"Webhooks_Deliveries", testWebhookDeliveries},
+		{"Webhooks_Replay", testWebhookReplay},
+		{"Concurrent_CreateReception", testConcurrentCreateReception},
+		{"Concurrent_AddProduct", testConcurrentAddProduct},
+		{"Concurrent_DeleteLastProduct", testConcurrentDeleteLastProduct},
+		{"Concurrent_DeleteProduct", testConcurrentDeleteProduct},
+		{"Race_AddProductAndClose", testRaceAddProductAndClose},
+		{"Race_DeleteLastProductAndClose", testRaceDeleteLastProductAndClose},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newPVZWithReception(t *testing.T, s store.Repository) (*model.PVZ, *model.Reception) {
+	t.Helper()
+	ctx := context.Background()
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+
+	r, err := s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	return pvz, r
+}
+
+func testCreatePVZ(t *testing.T, s store.Repository) {
+	pvz, err := s.CreatePVZ(context.Background(), model.Kazan)
+
+	require.NoError(t, err)
+	assert.NoError(t, uuid.Validate(pvz.ID))
+	assert.Equal(t, model.Kazan, pvz.City)
+	assert.False(t, pvz.RegistrationDate.IsZero())
+}
+
+func testCreatePVZCityNotAllowed(t *testing.T, s store.Repository) {
+	_, err := s.CreatePVZ(context.Background(), "Тверь")
+
+	assert.ErrorIs(t, err, store.ErrCityNotAllowed)
+}
+
+func testCities(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	name := model.City("Город-" + uuid.NewString())
+
+	city, err := s.AddCity(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, name, city.Name)
+	assert.True(t, city.Active)
+	assert.Empty(t, city.Aliases)
+
+	_, err = s.AddCity(ctx, name)
+	assert.ErrorIs(t, err, store.ErrCityAlreadyExists)
+
+	pvz, err := s.CreatePVZ(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, name, pvz.City)
+
+	city, err = s.SetCityActive(ctx, city.ID, false)
+	require.NoError(t, err)
+	assert.False(t, city.Active)
+
+	_, err = s.CreatePVZ(ctx, name)
+	assert.ErrorIs(t, err, store.ErrCityNotAllowed)
+
+	// PVZs of an inactive city stay.
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, name, got.PVZ.City)
+
+	assert.NotContains(t, cityNames(t, s, false), name)
+	assert.Contains(t, cityNames(t, s, true), name)
+
+	_, err = s.SetCityActive(ctx, city.ID, true)
+	require.NoError(t, err)
+
+	_, err = s.CreatePVZ(ctx, name)
+	assert.NoError(t, err)
+
+	_, err = s.SetCityActive(ctx, uuid.NewString(), false)
+	assert.ErrorIs(t, err, store.ErrCityNotFound)
+	_, err = s.RenameCity(ctx, uuid.NewString(), "Город")
+	assert.ErrorIs(t, err, store.ErrCityNotFound)
+}
+
+func testCitiesRename(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	suffix := uuid.NewString()
+	oldName := model.City("Ленинград-" + suffix)
+	newName := model.City("Петербург-" + suffix)
+
+	city, err := s.AddCity(ctx, oldName)
+	require.NoError(t, err)
+
+	pvz, err := s.CreatePVZ(ctx, oldName)
+	require.NoError(t, err)
+
+	city, err = s.RenameCity(ctx, city.ID, newName)
+	require.NoError(t, err)
+	assert.Equal(t, newName, city.Name)
+	assert.Equal(t, []model.City{oldName}, city.Aliases)
+
+	// Existing PVZs follow the rename.
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newName, got.PVZ.City)
+
+	// The old name is an alias of the current one.
+	pvz, err = s.CreatePVZ(ctx, oldName)
+	require.NoError(t, err)
+	assert.Equal(t, newName, pvz.City)
+
+	// Neither name can be taken by another city.
+	_, err = s.AddCity(ctx, oldName)
+	assert.ErrorIs(t, err, store.ErrCityAlreadyExists)
+
+	other, err := s.AddCity(ctx, model.City("Другой-"+suffix))
+	require.NoError(t, err)
+	_, err = s.RenameCity(ctx, other.ID, newName)
+	assert.ErrorIs(t, err, store.ErrCityAlreadyExists)
+	_, err = s.RenameCity(ctx, other.ID, oldName)
+	assert.ErrorIs(t, err, store.ErrCityAlreadyExists)
+
+	// Renaming back turns the alias into the name again.
+	city, err = s.RenameCity(ctx, city.ID, oldName)
+	require.NoError(t, err)
+	assert.Equal(t, oldName, city.Name)
+	assert.Equal(t, []model.City{newName}, city.Aliases)
+}
+
+// cityNames lists the names in the catalogue.
+func cityNames(t *testing.T, s store.Repository, includeInactive bool) []model.City {
+	t.Helper()
+
+	cities, err := s.ListCities(context.Background(), includeInactive)
+	require.NoError(t, err)
+
+	names := make([]model.City, len(cities))
+	for i, c := range cities {
+		names[i] = c.Name
+	}
+
+	return names
+}
+
+func testCreateReception(t *testing.T, s store.Repository) {
+	pvz, r := newPVZWithReception(t, s)
+
+	assert.NoError(t, uuid.Validate(r.ID))
+	assert.Equal(t, pvz.ID, r.PvzID)
+	assert.Equal(t, model.InProgress, r.Status)
+}
+
+func testCreateReceptionAlreadyExists(t *testing.T, s store.Repository) {
+	pvz, _ := newPVZWithReception(t, s)
+
+	_, err := s.CreateReception(context.Background(), pvz.ID)
+
+	assert.ErrorIs(t, err, store.ErrReceptionAlreadyExists)
+}
+
+func testCreateReceptionAfterClose(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, first := newPVZWithReception(t, s)
+
+	_, err := s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	second, err := s.CreateReception(ctx, pvz.ID)
+
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func testAddProduct(t *testing.T, s store.Repository) {
+	pvz, r := newPVZWithReception(t, s)
+
+	p, err := s.AddProduct(context.Background(), pvz.ID, store.ProductDraft{Type: model.Electronics})
+
+	require.NoError(t, err)
+	assert.NoError(t, uuid.Validate(p.ID))
+	assert.Equal(t, r.ID, p.ReceptionID)
+	assert.Equal(t, model.Electronics, p.Type)
+}
+
+func testAddProductTypeNotAllowed(t *testing.T, s store.Repository) {
+	pvz, _ := newPVZWithReception(t, s)
+
+	_, err := s.AddProduct(context.Background(), pvz.ID, store.ProductDraft{Type: "мебель"})
+
+	assert.ErrorIs(t, err, store.ErrProductTypeNotAllowed)
+}
+
+func testAddProductNoActiveReception(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing})
+	assert.ErrorIs(t, err, store.ErrNoActiveReception)
+
+	_, err = s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing})
+	assert.ErrorIs(t, err, store.ErrNoActiveReception)
+}
+
+func testAddProducts(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, r := newPVZWithReception(t, s)
+	clientID := uuid.NewString()
+
+	products, err := s.AddProducts(ctx, pvz.ID, []store.ProductDraft{
+		{Type: model.Shoes},
+		{ID: clientID, Type: model.Electronics},
+		{Type: model.Clothing},
+	})
+	require.NoError(t, err)
+	require.Len(t, products, 3)
+
+	assert.Equal(t, clientID, products[1].ID)
+	assert.NoError(t, uuid.Validate(products[0].ID))
+
+	var ids []string
+	for i, p := range products {
+		assert.Equal(t, r.ID, p.ReceptionID)
+		if i > 0 {
+			assert.True(t, p.DateTime.After(products[i-1].DateTime), "products keep the batch order")
+		}
+		ids = append(ids, p.ID)
+	}
+
+	assert.Equal(t, []model.ProductType{model.Shoes, model.Electronics, model.Clothing},
+		[]model.ProductType{products[0].Type, products[1].Type, products[2].Type})
+
+	// Every product gets its own event.
+	events := claimEvents(t, s, pvz.ID)
+	assert.Len(t, events, 4)
+
+	// The last item of the batch is the last product.
+	require.NoError(t, s.DeleteLastProduct(ctx, pvz.ID))
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	var remaining []string
+	for _, p := range got.Receptions[0].Products {
+		remaining = append(remaining, p.ID)
+	}
+
+	assert.Equal(t, ids[:2], remaining)
+}
+
+func testProductTypes(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+	code := model.ProductType("косметика-" + uuid.NewString())
+	weight := 500
+
+	pt, err := s.AddProductType(ctx, model.ProductTypeEntry{
+		Code:           code,
+		Names:          map[string]string{"ru": "Косметика", "en": "Cosmetics"},
+		Fragile:        true,
+		MaxWeightGrams: &weight,
+	})
+	require.NoError(t, err)
+	assert.True(t, pt.Active)
+	assert.Equal(t, "Cosmetics", pt.Names["en"])
+	require.NotNil(t, pt.MaxWeightGrams)
+	assert.Equal(t, 500, *pt.MaxWeightGrams)
+
+	_, err = s.AddProductType(ctx, model.ProductTypeEntry{Code: code, Names: map[string]string{"ru": "Косметика"}})
+	assert.ErrorIs(t, err, store.ErrProductTypeAlreadyExists)
+
+	// A new type is usable without a schema change.
+	product, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: code})
+	require.NoError(t, err)
+	assert.Equal(t, code, product.Type)
+
+	pt, err = s.UpdateProductType(ctx, model.ProductTypeEntry{Code: code, Names: map[string]string{"ru": "Уход"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ru": "Уход"}, pt.Names)
+	assert.False(t, pt.Fragile)
+	assert.Nil(t, pt.MaxWeightGrams)
+
+	_, err = s.SetProductTypeActive(ctx, code, false)
+	require.NoError(t, err)
+
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: code})
+	assert.ErrorIs(t, err, store.ErrProductTypeNotAllowed)
+
+	_, err = s.AddProducts(ctx, pvz.ID, []store.ProductDraft{{Type: model.Shoes}, {Type: code}, {Type: "мебель"}})
+	var typeErr *store.ProductTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, []int{1, 2}, typeErr.Indexes)
+
+	types, err := s.ListProductTypes(ctx, false)
+	require.NoError(t, err)
+	for _, pt := range types {
+		assert.NotEqual(t, code, pt.Code)
+	}
+
+	types, err = s.ListProductTypes(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(types, func(pt model.ProductTypeEntry) bool {
+		return pt.Code == code && !pt.Active
+	}))
+
+	_, err = s.SetProductTypeActive(ctx, "мебель-"+code, true)
+	assert.ErrorIs(t, err, store.ErrProductTypeNotFound)
+	_, err = s.UpdateProductType(ctx, model.ProductTypeEntry{Code: "мебель-" + code, Names: map[string]string{"ru": "Мебель"}})
+	assert.ErrorIs(t, err, store.ErrProductTypeNotFound)
+}
+
+func testAddProductsAtomic(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	existing, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		drafts []store.ProductDraft
+		err    error
+	}{
+		{"type not allowed", []store.ProductDraft{{Type: model.Shoes}, {Type: "мебель"}}, store.ErrProductTypeNotAllowed},
+		{"invalid ID", []store.ProductDraft{{Type: model.Shoes}, {ID: "abc", Type: model.Shoes}}, store.ErrInvalidProductID},
+		{"repeated ID", []store.ProductDraft{{ID: existing.ID, Type: model.Shoes}}, store.ErrProductAlreadyExists},
+		{"ID repeated in batch", func() []store.ProductDraft {
+			id := uuid.NewString()
+			return []store.ProductDraft{{ID: id, Type: model.Shoes}, {ID: id, Type: model.Clothing}}
+		}(), store.ErrProductAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AddProducts(ctx, pvz.ID, tt.drafts)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// A batch with a taken ID is rolled back as a whole.
+	_, err = s.AddProducts(ctx, pvz.ID, []store.ProductDraft{{Type: model.Clothing}, {ID: existing.ID, Type: model.Shoes}})
+	require.ErrorIs(t, err, store.ErrProductAlreadyExists)
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Receptions[0].Products, 1)
+
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	_, err = s.AddProducts(ctx, pvz.ID, []store.ProductDraft{{Type: model.Shoes}})
+	assert.ErrorIs(t, err, store.ErrNoActiveReception)
+}
+
+func testProductsBarcode(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, first := newPVZWithReception(t, s)
+	barcode := "4600000-" + uuid.NewString()
+
+	p, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes, Barcode: barcode, OrderID: "order-1"})
+	require.NoError(t, err)
+	assert.Equal(t, barcode, p.Barcode)
+	assert.Equal(t, "order-1", p.OrderID)
+
+	// Products without a barcode are not constrained.
+	_, err = s.AddProducts(ctx, pvz.ID, []store.ProductDraft{{Type: model.Shoes}, {Type: model.Shoes, OrderID: "order-1"}})
+	require.NoError(t, err)
+
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing, Barcode: barcode})
+	assert.ErrorIs(t, err, store.ErrBarcodeAlreadyExists)
+
+	_, err = s.AddProducts(ctx, pvz.ID, []store.ProductDraft{
+		{Type: model.Clothing, Barcode: barcode + "-2"},
+		{Type: model.Clothing, Barcode: barcode + "-2"},
+	})
+	assert.ErrorIs(t, err, store.ErrBarcodeAlreadyExists)
+
+	// The barcode is unique per reception only.
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	second, err := s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes, Barcode: barcode, OrderID: "order-2"})
+	require.NoError(t, err)
+
+	found, err := s.FindProductsByBarcode(ctx, barcode)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, second.ID, found[0].ReceptionID, "newest first")
+	assert.Equal(t, "order-2", found[0].OrderID)
+	assert.Equal(t, first.ID, found[1].ReceptionID)
+	assert.Equal(t, "order-1", found[1].OrderID)
+
+	found, err = s.FindProductsByBarcode(ctx, barcode+"-2")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Receptions, 2)
+	require.Len(t, got.Receptions[0].Products, 3)
+	assert.Equal(t, barcode, got.Receptions[0].Products[0].Barcode)
+	assert.Equal(t, "order-1", got.Receptions[0].Products[0].OrderID)
+	assert.Empty(t, got.Receptions[0].Products[1].Barcode)
+}
+
+func testDeleteLastProductLIFO(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	var ids []string
+	for _, pt := range []model.ProductType{model.Electronics, model.Clothing, model.Shoes} {
+		p, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: pt})
+		require.NoError(t, err)
+		ids = append(ids, p.ID)
+	}
+
+	require.NoError(t, s.DeleteLastProduct(ctx, pvz.ID))
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Receptions, 1)
+
+	var remaining []string
+	for _, p := range got.Receptions[0].Products {
+		remaining = append(remaining, p.ID)
+	}
+
+	assert.Equal(t, ids[:2], remaining)
+}
+
+func testDeleteLastProductNoProducts(t *testing.T, s store.Repository) {
+	pvz, _ := newPVZWithReception(t, s)
+
+	err := s.DeleteLastProduct(context.Background(), pvz.ID)
+
+	assert.ErrorIs(t, err, store.ErrNoProductsToDelete)
+}
+
+func testDeleteLastProductNoActiveReception(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	err = s.DeleteLastProduct(ctx, pvz.ID)
+	assert.ErrorIs(t, err, store.ErrNoActiveReception)
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Len(t, got.Receptions[0].Products, 1)
+}
+
+func testDeleteProduct(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	products, err := s.AddProducts(ctx, pvz.ID, []store.ProductDraft{
+		{Type: model.Shoes}, {Type: model.Clothing, Barcode: "4600000000017"}, {Type: model.Electronics},
+	})
+	require.NoError(t, err)
+
+	pvzID, err := s.ProductPVZ(ctx, products[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, pvz.ID, pvzID)
+
+	deleted, err := s.DeleteProduct(ctx, pvz.ID, products[1].ID, "wrong item scanned")
+	require.NoError(t, err)
+	assert.Equal(t, products[1].ID, deleted.ID)
+	assert.Equal(t, "4600000000017", deleted.Barcode)
+
+	_, err = s.DeleteProduct(ctx, pvz.ID, products[1].ID, "again")
+	assert.ErrorIs(t, err, store.ErrProductNotFound)
+	_, err = s.ProductPVZ(ctx, products[1].ID)
+	assert.ErrorIs(t, err, store.ErrProductNotFound)
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, productIDs(products[0], products[2]), productIDs(got.Receptions[0].Products...))
+
+	// The order of the rest is kept, so LIFO deletes go on as before.
+	require.NoError(t, s.DeleteLastProduct(ctx, pvz.ID))
+	got, err = s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, productIDs(products[0]), productIDs(got.Receptions[0].Products...))
+
+	events, err := s.FetchAuditEvents(ctx, store.AuditFilter{PVZID: pvz.ID, Page: 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Empty(t, events[0].Reason, "delete_last_product has no reason")
+	assert.Equal(t, model.AuditProductDeleted, events[1].Action)
+	assert.Equal(t, products[1].ID, events[1].ProductID)
+	assert.Equal(t, "wrong item scanned", events[1].Reason)
+
+	_, err = s.DeleteProduct(ctx, pvz.ID, uuid.NewString(), "unknown")
+	assert.ErrorIs(t, err, store.ErrProductNotFound)
+}
+
+func productIDs(products ...model.Product) []string {
+	ids := make([]string, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func testDeleteProductClosedReception(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	product, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	// The PVZ has no open reception, the product's is closed all the same.
+	_, err = s.DeleteProduct(ctx, pvz.ID, product.ID, "too late")
+	assert.ErrorIs(t, err, store.ErrProductReceptionClosed)
+
+	_, err = s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	_, err = s.DeleteProduct(ctx, pvz.ID, product.ID, "too late")
+	assert.ErrorIs(t, err, store.ErrProductReceptionClosed)
+
+	// A product of another PVZ is not found through this one.
+	other, _ := newPVZWithReception(t, s)
+	foreign, err := s.AddProduct(ctx, other.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+
+	_, err = s.DeleteProduct(ctx, pvz.ID, foreign.ID, "wrong PVZ")
+	assert.ErrorIs(t, err, store.ErrProductNotFound)
+}
+
+func testCloseLastReception(t *testing.T, s store.Repository) {
+	pvz, r := newPVZWithReception(t, s)
+
+	closed, err := s.CloseLastReception(context.Background(), pvz.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, r.ID, closed.ID)
+	assert.Equal(t, pvz.ID, closed.PvzID)
+	assert.Equal(t, model.Closed, closed.Status)
+}
+
+func testCloseLastReceptionNoActiveReception(t *testing.T, s store.Repository) {
+	pvz, err := s.CreatePVZ(context.Background(), model.SPB)
+	require.NoError(t, err)
+
+	_, err = s.CloseLastReception(context.Background(), pvz.ID)
+
+	assert.ErrorIs(t, err, store.ErrNoActiveReception)
+}
+
+func testFetchPVZ(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, r := newPVZWithReception(t, s)
+
+	p, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+	require.NoError(t, err)
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, pvz.ID, got.PVZ.ID)
+	assert.Equal(t, pvz.City, got.PVZ.City)
+	require.Len(t, got.Receptions, 1)
+	assert.Equal(t, r.ID, got.Receptions[0].Reception.ID)
+	assert.Equal(t, model.InProgress, got.Receptions[0].Reception.Status)
+	require.Len(t, got.Receptions[0].Products, 1)
+	assert.Equal(t, p.ID, got.Receptions[0].Products[0].ID)
+	assert.Equal(t, model.Electronics, got.Receptions[0].Products[0].Type)
+}
+
+func testFetchPVZNotFound(t *testing.T, s store.Repository) {
+	_, err := s.FetchPVZ(context.Background(), uuid.NewString())
+
+	assert.ErrorIs(t, err, store.ErrPVZNotFound)
+}
+
+func testMutationsPVZNotFound(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	for _, pvzID := range []string{uuid.NewString(), "not-a-uuid"} {
+		_, err := s.CreateReception(ctx, pvzID)
+		assert.ErrorIs(t, err, store.ErrPVZNotFound, pvzID)
+
+		_, err = s.AddProduct(ctx, pvzID, store.ProductDraft{Type: model.Electronics})
+		assert.ErrorIs(t, err, store.ErrPVZNotFound, pvzID)
+
+		err = s.DeleteLastProduct(ctx, pvzID)
+		assert.ErrorIs(t, err, store.ErrPVZNotFound, pvzID)
+
+		_, err = s.CloseLastReception(ctx, pvzID)
+		assert.ErrorIs(t, err, store.ErrPVZNotFound, pvzID)
+	}
+}
+
+func testFetchPVZListPageBoundaries(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	productsPerPVZ := map[string]int{}
+
+	for i := 0; i < 5; i++ {
+		pvz, _ := newPVZWithReception(t, s)
+
+		for j := 0; j < 7; j++ {
+			_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing})
+			require.NoError(t, err)
+		}
+
+		productsPerPVZ[pvz.ID] = 7
+	}
+
+	seen := map[string]int{}
+	var prev *model.PVZ
+
+	for page := 1; ; page++ {
+		result, err := s.FetchPVZList(ctx, &start, nil, page, 2)
+		require.NoError(t, err)
+
+		if len(result) == 0 {
+			break
+		}
+
+		assert.LessOrEqual(t, len(result), 2)
+
+		for _, item := range result {
+			seen[item.PVZ.ID]++
+
+			if prev != nil {
+				assert.True(t, pvzOrdered(*prev, item.PVZ), "PVZs must be ordered by registration date and ID")
+			}
+			pvz := item.PVZ
+			prev = &pvz
+
+			if want, ok := productsPerPVZ[item.PVZ.ID]; ok {
+				require.Len(t, item.Receptions, 1)
+				assert.Len(t, item.Receptions[0].Products, want)
+			}
+		}
+	}
+
+	for id := range productsPerPVZ {
+		assert.Equal(t, 1, seen[id], "PVZ %s must appear on exactly one page", id)
+	}
+}
+
+func testFetchPVZListDateRange(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	past := time.Now().Add(-48 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	result, err := s.FetchPVZList(ctx, &past, &yesterday, 1, 30)
+	require.NoError(t, err)
+
+	for _, item := range result {
+		assert.NotEqual(t, pvz.ID, item.PVZ.ID, "PVZ without receptions in range must be filtered out")
+	}
+
+	start := time.Now().Add(-time.Minute)
+	end := time.Now().Add(time.Minute)
+
+	found := false
+	for page := 1; !found; page++ {
+		result, err := s.FetchPVZList(ctx, &start, &end, page, 30)
+		require.NoError(t, err)
+
+		if len(result) == 0 {
+			break
+		}
+
+		for _, item := range result {
+			if item.PVZ.ID == pvz.ID {
+				found = true
+				assert.Len(t, item.Receptions, 1)
+			}
+		}
+	}
+
+	assert.True(t, found, "PVZ with a reception in range must be listed")
+}
+
+func testFetchPVZListAfter(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	created := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		pvz, _ := newPVZWithReception(t, s)
+		created[pvz.ID] = true
+	}
+
+	seen := map[string]int{}
+	var after *store.PVZCursor
+	var prev *model.PVZ
+
+	for {
+		result, next, err := s.FetchPVZListAfter(ctx, &start, nil, after, 2)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(result), 2)
+
+		for _, item := range result {
+			seen[item.PVZ.ID]++
+
+			if prev != nil {
+				assert.True(t, pvzOrdered(*prev, item.PVZ), "PVZs must be ordered by registration date and ID")
+			}
+			pvz := item.PVZ
+			prev = &pvz
+		}
+
+		if next == nil {
+			break
+		}
+
+		after = next
+	}
+
+	for id := range created {
+		assert.Equal(t, 1, seen[id], "PVZ %s must appear exactly once", id)
+	}
+}
+
+func testUsers(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	email := uuid.NewString() + "@example.com"
+
+	u, err := s.CreateUser(ctx, email, "hash", model.Employee)
+	require.NoError(t, err)
+	assert.NoError(t, uuid.Validate(u.ID))
+
+	_, err = s.CreateUser(ctx, email, "other", model.Moderator)
+	assert.ErrorIs(t, err, store.ErrUserAlreadyExists)
+
+	got, err := s.GetUserByEmail(ctx, email)
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, got.ID)
+	assert.Equal(t, "hash", got.PasswordHash)
+	assert.Equal(t, model.Employee, got.Role)
+
+	_, err = s.GetUserByEmail(ctx, uuid.NewString()+"@example.com")
+	assert.ErrorIs(t, err, store.ErrUserNotFound)
+}
+
+func testUsersRoleNotAllowed(t *testing.T, s store.Repository) {
+	_, err := s.CreateUser(context.Background(), uuid.NewString()+"@example.com", "hash", "root")
+
+	assert.ErrorIs(t, err, store.ErrRoleNotAllowed)
+}
+
+func newUser(t *testing.T, s store.Repository) *model.User {
+	t.Helper()
+
+	u, err := s.CreateUser(context.Background(), uuid.NewString()+"@example.com", "hash", model.Employee)
+	require.NoError(t, err)
+
+	return u
+}
+
+// newRefreshToken returns an unsaved token whose access token is still valid.
+func newRefreshToken() *model.RefreshToken {
+	return &model.RefreshToken{
+		TokenHash:       uuid.NewString(),
+		AccessJTI:       uuid.NewString(),
+		AccessExpiresAt: time.Now().Add(time.Hour),
+		ExpiresAt:       time.Now().Add(time.Hour),
+	}
+}
+
+func testRefreshTokenRotate(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	first := newRefreshToken()
+	first.UserID = u.ID
+	first.Role = u.Role
+	require.NoError(t, s.CreateRefreshToken(ctx, first))
+	assert.NoError(t, uuid.Validate(first.FamilyID))
+
+	next := newRefreshToken()
+	require.NoError(t, s.RotateRefreshToken(ctx, first.TokenHash, next))
+
+	assert.Equal(t, first.FamilyID, next.FamilyID)
+	assert.Equal(t, u.ID, next.UserID)
+	assert.Equal(t, model.Employee, next.Role)
+
+	require.NoError(t, s.RotateRefreshToken(ctx, next.TokenHash, newRefreshToken()))
+
+	err := s.RotateRefreshToken(ctx, uuid.NewString(), newRefreshToken())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+}
+
+func testRefreshTokenRotateCurrentRole(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	// Issued while the user had another role.
+	first := newRefreshToken()
+	first.UserID = u.ID
+	first.Role = model.Moderator
+	require.NoError(t, s.CreateRefreshToken(ctx, first))
+
+	next := newRefreshToken()
+	require.NoError(t, s.RotateRefreshToken(ctx, first.TokenHash, next))
+	assert.Equal(t, model.Employee, next.Role)
+
+	// Later rotations keep the current role.
+	last := newRefreshToken()
+	require.NoError(t, s.RotateRefreshToken(ctx, next.TokenHash, last))
+	assert.Equal(t, model.Employee, last.Role)
+}
+
+func testRefreshTokenReuseRevokesFamily(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	first := newRefreshToken()
+	first.UserID = u.ID
+	first.Role = u.Role
+	require.NoError(t, s.CreateRefreshToken(ctx, first))
+
+	second := newRefreshToken()
+	require.NoError(t, s.RotateRefreshToken(ctx, first.TokenHash, second))
+
+	err := s.RotateRefreshToken(ctx, first.TokenHash, newRefreshToken())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenReused)
+
+	// The legitimate successor dies with the family, as do access tokens.
+	err = s.RotateRefreshToken(ctx, second.TokenHash, newRefreshToken())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+
+	for _, jti := range []string{first.AccessJTI, second.AccessJTI} {
+		revoked, err := s.IsAccessTokenRevoked(ctx, jti)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	}
+}
+
+func testRefreshTokenExpired(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	token := newRefreshToken()
+	token.UserID = u.ID
+	token.Role = u.Role
+	token.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, s.CreateRefreshToken(ctx, token))
+
+	err := s.RotateRefreshToken(ctx, token.TokenHash, newRefreshToken())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+}
+
+func testRefreshTokenRevokeFamily(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	token := newRefreshToken()
+	token.UserID = u.ID
+	token.Role = u.Role
+	require.NoError(t, s.CreateRefreshToken(ctx, token))
+
+	other := newRefreshToken()
+	other.UserID = u.ID
+	other.Role = u.Role
+	require.NoError(t, s.CreateRefreshToken(ctx, other))
+
+	// Another user cannot revoke the token.
+	err := s.RevokeRefreshTokenFamily(ctx, newUser(t, s).ID, token.TokenHash)
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+	err = s.RevokeRefreshTokenFamily(ctx, "", token.TokenHash)
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+
+	require.NoError(t, s.RevokeRefreshTokenFamily(ctx, u.ID, token.TokenHash))
+
+	err = s.RotateRefreshToken(ctx, token.TokenHash, newRefreshToken())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+
+	// Other logins of the same user are untouched.
+	assert.NoError(t, s.RotateRefreshToken(ctx, other.TokenHash, newRefreshToken()))
+
+	err = s.RevokeRefreshTokenFamily(ctx, u.ID, uuid.NewString())
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+}
+
+func testTokensPruned(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	expired := newRefreshToken()
+	expired.UserID = u.ID
+	expired.Role = u.Role
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, s.CreateRefreshToken(ctx, expired))
+
+	// Writing the next token drops the expired one.
+	fresh := newRefreshToken()
+	fresh.UserID = u.ID
+	fresh.Role = u.Role
+	require.NoError(t, s.CreateRefreshToken(ctx, fresh))
+
+	err := s.RevokeRefreshTokenFamily(ctx, u.ID, expired.TokenHash)
+	assert.ErrorIs(t, err, store.ErrRefreshTokenInvalid)
+
+	expiredJTI := uuid.NewString()
+	require.NoError(t, s.RevokeAccessToken(ctx, expiredJTI, time.Now().Add(-time.Minute)))
+	require.NoError(t, s.RevokeAccessToken(ctx, uuid.NewString(), time.Now().Add(time.Minute)))
+
+	revoked, err := s.IsAccessTokenRevoked(ctx, expiredJTI)
+	require.NoError(t, err)
+	assert.False(t, revoked, "revocations of expired access tokens are dropped")
+}
+
+func testRefreshTokenUnknownUser(t *testing.T, s store.Repository) {
+	token := newRefreshToken()
+	token.UserID = uuid.NewString()
+	token.Role = model.Employee
+
+	err := s.CreateRefreshToken(context.Background(), token)
+
+	assert.ErrorIs(t, err, store.ErrUserNotFound)
+}
+
+func testAccessTokenRevoke(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	jti := uuid.NewString()
+
+	revoked, err := s.IsAccessTokenRevoked(ctx, jti)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, s.RevokeAccessToken(ctx, jti, time.Now().Add(time.Minute)))
+	require.NoError(t, s.RevokeAccessToken(ctx, jti, time.Now().Add(time.Minute)))
+
+	revoked, err = s.IsAccessTokenRevoked(ctx, jti)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testAssignments(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	first, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+	second, err := s.CreatePVZ(ctx, model.Kazan)
+	require.NoError(t, err)
+
+	assigned, err := s.IsAssigned(ctx, u.ID, first.ID)
+	require.NoError(t, err)
+	assert.False(t, assigned)
+
+	require.NoError(t, s.AssignPVZ(ctx, u.ID, second.ID))
+	require.NoError(t, s.AssignPVZ(ctx, u.ID, first.ID))
+	require.NoError(t, s.AssignPVZ(ctx, u.ID, first.ID))
+
+	assigned, err = s.IsAssigned(ctx, u.ID, first.ID)
+	require.NoError(t, err)
+	assert.True(t, assigned)
+
+	pvzs, err := s.ListAssignedPVZs(ctx, u.ID)
+	require.NoError(t, err)
+	require.Len(t, pvzs, 2)
+	assert.Equal(t, first.ID, pvzs[0].ID)
+	assert.Equal(t, second.ID, pvzs[1].ID)
+
+	require.NoError(t, s.UnassignPVZ(ctx, u.ID, first.ID))
+
+	assigned, err = s.IsAssigned(ctx, u.ID, first.ID)
+	require.NoError(t, err)
+	assert.False(t, assigned)
+
+	err = s.UnassignPVZ(ctx, u.ID, first.ID)
+	assert.ErrorIs(t, err, store.ErrAssignmentNotFound)
+
+	pvzs, err = s.ListAssignedPVZs(ctx, uuid.NewString())
+	require.NoError(t, err)
+	assert.Empty(t, pvzs)
+}
+
+func testAssignmentsInvalid(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+
+	moderator, err := s.CreateUser(ctx, uuid.NewString()+"@example.com", "hash", model.Moderator)
+	require.NoError(t, err)
+
+	err = s.AssignPVZ(ctx, moderator.ID, pvz.ID)
+	assert.ErrorIs(t, err, store.ErrUserNotEmployee)
+
+	err = s.AssignPVZ(ctx, uuid.NewString(), pvz.ID)
+	assert.ErrorIs(t, err, store.ErrUserNotFound)
+
+	err = s.AssignPVZ(ctx, u.ID, uuid.NewString())
+	assert.ErrorIs(t, err, store.ErrPVZNotFound)
+
+	assigned, err := s.IsAssigned(ctx, "not-a-uuid", pvz.ID)
+	require.NoError(t, err)
+	assert.False(t, assigned)
+
+	_, err = s.IsAssigned(ctx, u.ID, uuid.NewString())
+	assert.ErrorIs(t, err, store.ErrPVZNotFound)
+
+	_, err = s.IsAssigned(ctx, u.ID, "not-a-uuid")
+	assert.ErrorIs(t, err, store.ErrPVZNotFound)
+}
+
+func testAuditMutations(t *testing.T, s store.Repository) {
+	actor := model.Actor{UserID: uuid.NewString(), Role: string(model.Employee), RequestID: "req-1"}
+	ctx := store.WithActor(context.Background(), actor)
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+	reception, err := s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	product, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteLastProduct(ctx, pvz.ID))
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	// Failed mutations leave no trace.
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.ErrorIs(t, err, store.ErrNoActiveReception)
+
+	events, err := s.FetchAuditEvents(ctx, store.AuditFilter{PVZID: pvz.ID, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+
+	actions := make([]model.AuditAction, len(events))
+	for i, ev := range events {
+		actions[i] = ev.Action
+		assert.Equal(t, actor.UserID, ev.ActorID)
+		assert.Equal(t, actor.Role, ev.ActorRole)
+		assert.Equal(t, actor.RequestID, ev.RequestID)
+		assert.Equal(t, pvz.ID, ev.PVZID)
+	}
+
+	assert.Equal(t, []model.AuditAction{
+		model.AuditReceptionClosed,
+		model.AuditProductDeleted,
+		model.AuditProductAdded,
+		model.AuditReceptionOpened,
+		model.AuditPVZCreated,
+	}, actions)
+
+	closed := events[0]
+	assert.Equal(t, reception.ID, closed.ReceptionID)
+	assert.JSONEq(t, `"in_progress"`, jsonField(t, closed.Before, "status"))
+	assert.JSONEq(t, `"close"`, jsonField(t, closed.After, "status"))
+
+	deleted := events[1]
+	assert.Equal(t, product.ID, deleted.ProductID)
+	assert.Nil(t, deleted.After)
+	assert.JSONEq(t, `"`+product.ID+`"`, jsonField(t, deleted.Before, "id"))
+
+	added := events[2]
+	assert.Equal(t, product.ID, added.ProductID)
+	assert.Nil(t, added.Before)
+	assert.JSONEq(t, `"`+string(model.Electronics)+`"`, jsonField(t, added.After, "type"))
+
+	created := events[4]
+	assert.Empty(t, created.ReceptionID)
+	assert.JSONEq(t, `"`+string(model.Moscow)+`"`, jsonField(t, created.After, "city"))
+}
+
+func testAuditFilters(t *testing.T, s store.Repository) {
+	alice := store.WithActor(context.Background(), model.Actor{UserID: uuid.NewString(), Role: string(model.Moderator)})
+	bob := store.WithActor(context.Background(), model.Actor{UserID: uuid.NewString(), Role: string(model.Moderator)})
+
+	// The pauses keep the events apart for the time range checks.
+	first, err := s.CreatePVZ(alice, model.Moscow)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = s.CreatePVZ(bob, model.Kazan)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	third, err := s.CreatePVZ(alice, model.SPB)
+	require.NoError(t, err)
+
+	events, err := s.FetchAuditEvents(alice, store.AuditFilter{ActorID: store.ActorFromContext(alice).UserID, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, third.ID, events[0].PVZID)
+	assert.Equal(t, first.ID, events[1].PVZID)
+
+	events, err = s.FetchAuditEvents(alice, store.AuditFilter{ActorID: store.ActorFromContext(alice).UserID, Page: 2, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, first.ID, events[0].PVZID)
+
+	from := first.RegistrationDate.Add(time.Microsecond)
+	events, err = s.FetchAuditEvents(alice, store.AuditFilter{ActorID: store.ActorFromContext(alice).UserID, From: &from, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, third.ID, events[0].PVZID)
+
+	to := third.RegistrationDate.Add(-time.Microsecond)
+	events, err = s.FetchAuditEvents(alice, store.AuditFilter{ActorID: store.ActorFromContext(alice).UserID, To: &to, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, first.ID, events[0].PVZID)
+
+	events, err = s.FetchAuditEvents(alice, store.AuditFilter{PVZID: uuid.NewString(), Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func testOutboxEvents(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, reception := newPVZWithReception(t, s)
+
+	product, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteLastProduct(ctx, pvz.ID))
+	_, err = s.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	// Failed mutations enqueue nothing.
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+	require.ErrorIs(t, err, store.ErrNoActiveReception)
+
+	events := claimEvents(t, s, pvz.ID)
+	require.Len(t, events, 4)
+
+	types := make([]model.EventType, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+		assert.Equal(t, pvz.ID, ev.PVZID)
+		assert.Zero(t, ev.Attempts)
+	}
+
+	assert.Equal(t, []model.EventType{
+		model.EventReceptionOpened,
+		model.EventProductAdded,
+		model.EventProductDeleted,
+		model.EventReceptionClosed,
+	}, types)
+
+	assert.JSONEq(t, `"`+reception.ID+`"`, jsonField(t, events[0].Data, "id"))
+	assert.JSONEq(t, `"`+product.ID+`"`, jsonField(t, events[1].Data, "id"))
+	assert.JSONEq(t, `"`+product.ID+`"`, jsonField(t, events[2].Data, "id"))
+	assert.JSONEq(t, `"close"`, jsonField(t, events[3].Data, "status"))
+
+	// Claimed events stay hidden while leased.
+	assert.Empty(t, claimEvents(t, s, pvz.ID))
+}
+
+func testOutboxRetry(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing})
+	require.NoError(t, err)
+
+	events := claimEvents(t, s, pvz.ID)
+	require.Len(t, events, 2)
+
+	opened, added := events[0], events[1]
+
+	require.NoError(t, s.MarkEventPublished(ctx, opened.ID))
+	require.NoError(t, s.MarkEventFailed(ctx, added.ID, "connection refused", time.Now().Add(-time.Second)))
+
+	events = claimEvents(t, s, pvz.ID)
+	require.Len(t, events, 1)
+	assert.Equal(t, added.ID, events[0].ID)
+	assert.Equal(t, 1, events[0].Attempts)
+
+	require.NoError(t, s.MarkEventFailed(ctx, added.ID, "timeout", time.Now().Add(time.Hour)))
+	assert.Empty(t, claimEvents(t, s, pvz.ID), "event must wait for its retry time")
+}
+
+func testPVZEventStream(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	_, err := s.LastPVZEventSeq(ctx, uuid.NewString())
+	require.ErrorIs(t, err, store.ErrPVZNotFound)
+	_, err = s.FetchPVZEvents(ctx, uuid.NewString(), 0, 10)
+	require.ErrorIs(t, err, store.ErrPVZNotFound)
+
+	pvz, err := s.CreatePVZ(ctx, model.Moscow)
+	require.NoError(t, err)
+
+	last, err := s.LastPVZEventSeq(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Zero(t, last)
+
+	other, _ := newPVZWithReception(t, s)
+	_, err = s.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	_, err = s.AddProduct(ctx, other.ID, store.ProductDraft{Type: model.Shoes})
+	require.NoError(t, err)
+	_, err = s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+	require.NoError(t, err)
+
+	// Publishing does not hide events from streams.
+	claimEvents(t, s, pvz.ID)
+
+	var events []model.Event
+
+	// Events are hidden until sequenced, and another run may hold the
+	// sequencing lock for a moment.
+	require.Eventually(t, func() bool {
+		if s.SequenceEvents(ctx) != nil {
+			return false
+		}
+
+		events, err = s.FetchPVZEvents(ctx, pvz.ID, 0, 10)
+		return err == nil && len(events) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, model.EventReceptionOpened, events[0].Type)
+	assert.Equal(t, model.EventProductAdded, events[1].Type)
+	assert.Less(t, events[0].Seq, events[1].Seq)
+
+	for _, ev := range events {
+		assert.Equal(t, pvz.ID, ev.PVZID)
+	}
+
+	last, err = s.LastPVZEventSeq(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, events[1].Seq, last)
+
+	after, err := s.FetchPVZEvents(ctx, pvz.ID, events[0].Seq, 10)
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	assert.Equal(t, events[1].ID, after[0].ID)
+
+	limited, err := s.FetchPVZEvents(ctx, pvz.ID, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, events[0].ID, limited[0].ID)
+
+	none, err := s.FetchPVZEvents(ctx, pvz.ID, last, 10)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+// claimEvents claims every available event and returns those of the PVZ.
+// Events of other tests stay leased, which does not affect them.
+func claimEvents(t *testing.T, s store.Repository, pvzID string) []model.Event {
+	t.Helper()
+
+	var result []model.Event
+
+	for {
+		events, err := s.ClaimEvents(context.Background(), 100, time.Minute)
+		require.NoError(t, err)
+
+		for _, ev := range events {
+			if ev.PVZID == pvzID {
+				result = append(result, ev)
+			}
+		}
+
+		if len(events) < 100 {
+			return result
+		}
+	}
+}
+
+func testIdempotency(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	key := store.IdempotencyKey{
+		UserID:      uuid.NewString(),
+		Key:         "scan-1",
+		Fingerprint: "fp-1",
+		ExpiresAt:   time.Now().Add(time.Hour),
+		LockedUntil: time.Now().Add(time.Minute),
+	}
+
+	resp, err := s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, resp, "a new key is reserved")
+
+	_, err = s.ReserveIdempotencyKey(ctx, key)
+	assert.ErrorIs(t, err, store.ErrIdempotencyKeyInUse)
+
+	// Releasing lets a retry run the request again.
+	require.NoError(t, s.ReleaseIdempotencyKey(ctx, key.UserID, key.Key))
+	resp, err = s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	saved := store.StoredResponse{Status: 201, ContentType: "application/json", Body: []byte(`{"id":"p-1"}`)}
+	require.NoError(t, s.SaveIdempotentResponse(ctx, key.UserID, key.Key, saved))
+
+	resp, err = s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, &saved, resp)
+
+	// A stored response is not released.
+	require.NoError(t, s.ReleaseIdempotencyKey(ctx, key.UserID, key.Key))
+	resp, err = s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, &saved, resp)
+
+	other := key
+	other.Fingerprint = "fp-2"
+	_, err = s.ReserveIdempotencyKey(ctx, other)
+	assert.ErrorIs(t, err, store.ErrIdempotencyKeyMismatch)
+
+	// The same key of another user is unrelated.
+	other.UserID = uuid.NewString()
+	resp, err = s.ReserveIdempotencyKey(ctx, other)
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+}
+
+func testIdempotencyExpiry(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	key := store.IdempotencyKey{
+		UserID:      uuid.NewString(),
+		Key:         "scan-1",
+		Fingerprint: "fp-1",
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+
+	_, err := s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveIdempotentResponse(ctx, key.UserID, key.Key, store.StoredResponse{Status: 201}))
+
+	// An expired key is free again, even for a different request.
+	key.Fingerprint = "fp-2"
+	key.ExpiresAt = time.Now().Add(time.Hour)
+
+	resp, err := s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+}
+
+func testIdempotencyLease(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	key := store.IdempotencyKey{
+		UserID:      uuid.NewString(),
+		Key:         "scan-1",
+		Fingerprint: "fp-1",
+		ExpiresAt:   time.Now().Add(time.Hour),
+		LockedUntil: time.Now().Add(-time.Second),
+	}
+
+	_, err := s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+
+	// The first request never finished; once its lease is over a retry
+	// takes the key over and holds it for its own lease.
+	key.LockedUntil = time.Now().Add(time.Minute)
+
+	resp, err := s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	_, err = s.ReserveIdempotencyKey(ctx, key)
+	assert.ErrorIs(t, err, store.ErrIdempotencyKeyInUse)
+
+	// A stored response outlives the lease.
+	saved := store.StoredResponse{Status: 201, ContentType: "application/json", Body: []byte(`{}`)}
+	require.NoError(t, s.SaveIdempotentResponse(ctx, key.UserID, key.Key, saved))
+
+	key.LockedUntil = time.Now().Add(-time.Second)
+	resp, err = s.ReserveIdempotencyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, &saved, resp)
+}
+
+func testWebhooks(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	w, err := s.CreateWebhook(ctx, "http://localhost:8081/hook", "0123456789abcdef",
+		[]model.EventType{model.EventReceptionClosed, model.EventProductAdded, model.EventReceptionClosed})
+	require.NoError(t, err)
+	assert.Equal(t, []model.EventType{model.EventReceptionClosed, model.EventProductAdded}, w.EventTypes)
+
+	webhooks, err := s.ListWebhooks(ctx)
+	require.NoError(t, err)
+
+	found := false
+	for _, got := range webhooks {
+		if got.ID == w.ID {
+			found = true
+			assert.Equal(t, w.URL, got.URL)
+			assert.Equal(t, w.Secret, got.Secret)
+			assert.Equal(t, w.EventTypes, got.EventTypes)
+		}
+	}
+	assert.True(t, found)
+
+	require.NoError(t, s.DeleteWebhook(ctx, w.ID))
+	assert.ErrorIs(t, s.DeleteWebhook(ctx, w.ID), store.ErrWebhookNotFound)
+
+	_, err = s.CreateWebhook(ctx, "http://localhost:8081/hook", "0123456789abcdef", []model.EventType{"pvz.deleted"})
+	assert.ErrorIs(t, err, store.ErrEventTypeNotAllowed)
+
+	_, err = s.ListWebhookDeliveries(ctx, w.ID, store.DeliveryFilter{Page: 1, Limit: 10})
+	assert.ErrorIs(t, err, store.ErrWebhookNotFound)
+}
+
+func testWebhookDeliveries(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	closed, err := s.CreateWebhook(ctx, "http://localhost:8081/closed", "0123456789abcdef", []model.EventType{model.EventReceptionClosed})
+	require.NoError(t, err)
+	added, err := s.CreateWebhook(ctx, "http://localhost:8081/added", "fedcba9876543210", []model.EventType{model.EventProductAdded})
+	require.NoError(t, err)
+
+	ev, err := store.NewEvent(model.EventProductAdded, uuid.NewString(), map[string]string{"id": "p-1"}, time.Now())
+	require.NoError(t, err)
+
+	require.NoError(t, s.EnqueueWebhookDeliveries(ctx, *ev))
+	require.NoError(t, s.EnqueueWebhookDeliveries(ctx, *ev), "enqueueing an event twice must be a no-op")
+
+	deliveries, err := s.ListWebhookDeliveries(ctx, closed.ID, store.DeliveryFilter{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	jobs := claimDeliveries(t, s, added.ID)
+	require.Len(t, jobs, 1)
+
+	job := jobs[0]
+	assert.Equal(t, added.URL, job.URL)
+	assert.Equal(t, added.Secret, job.Secret)
+	assert.Equal(t, ev.ID, job.Delivery.EventID)
+	assert.Equal(t, model.DeliveryPending, job.Delivery.Status)
+	assert.JSONEq(t, `"`+ev.ID+`"`, jsonField(t, job.Delivery.Payload, "id"))
+
+	assert.Empty(t, claimDeliveries(t, s, added.ID), "claimed deliveries stay hidden while leased")
+
+	require.NoError(t, s.FailWebhookDelivery(ctx, job.Delivery.ID, store.DeliveryFailure{
+		StatusCode: 502,
+		Error:      "bad gateway",
+		RetryAt:    time.Now().Add(-time.Second),
+	}))
+
+	jobs = claimDeliveries(t, s, added.ID)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 1, jobs[0].Delivery.Attempts)
+	assert.Equal(t, 502, jobs[0].Delivery.LastStatusCode)
+	assert.Equal(t, "bad gateway", jobs[0].Delivery.LastError)
+
+	require.NoError(t, s.CompleteWebhookDelivery(ctx, job.Delivery.ID, 204))
+
+	deliveries, err = s.ListWebhookDeliveries(ctx, added.ID, store.DeliveryFilter{Status: model.DeliverySucceeded, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, 204, deliveries[0].LastStatusCode)
+	assert.Empty(t, deliveries[0].LastError)
+
+	deliveries, err = s.ListWebhookDeliveries(ctx, added.ID, store.DeliveryFilter{Status: model.DeliveryDead, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func testWebhookReplay(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	w, err := s.CreateWebhook(ctx, "http://localhost:8081/hook", "0123456789abcdef", []model.EventType{model.EventReceptionOpened})
+	require.NoError(t, err)
+
+	ev, err := store.NewEvent(model.EventReceptionOpened, uuid.NewString(), map[string]string{"id": "r-1"}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, s.EnqueueWebhookDeliveries(ctx, *ev))
+
+	jobs := claimDeliveries(t, s, w.ID)
+	require.Len(t, jobs, 1)
+	id := jobs[0].Delivery.ID
+
+	_, err = s.ReplayWebhookDelivery(ctx, w.ID, id)
+	assert.ErrorIs(t, err, store.ErrDeliveryNotDead)
+
+	require.NoError(t, s.FailWebhookDelivery(ctx, id, store.DeliveryFailure{
+		Error:   "connection refused",
+		RetryAt: time.Now().Add(-time.Second),
+		Dead:    true,
+	}))
+
+	assert.Empty(t, claimDeliveries(t, s, w.ID), "dead deliveries are not retried")
+
+	replayed, err := s.ReplayWebhookDelivery(ctx, w.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryPending, replayed.Status)
+	assert.Zero(t, replayed.Attempts)
+
+	jobs = claimDeliveries(t, s, w.ID)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, id, jobs[0].Delivery.ID)
+
+	_, err = s.ReplayWebhookDelivery(ctx, w.ID, uuid.NewString())
+	assert.ErrorIs(t, err, store.ErrDeliveryNotFound)
+
+	_, err = s.ReplayWebhookDelivery(ctx, uuid.NewString(), id)
+	assert.ErrorIs(t, err, store.ErrWebhookNotFound)
+}
+
+// claimDeliveries claims every due delivery and returns those of the webhook.
+func claimDeliveries(t *testing.T, s store.Repository, webhookID string) []store.DeliveryJob {
+	t.Helper()
+
+	var result []store.DeliveryJob
+
+	for {
+		jobs, err := s.ClaimWebhookDeliveries(context.Background(), 100, time.Minute)
+		require.NoError(t, err)
+
+		for _, job := range jobs {
+			if job.Delivery.WebhookID == webhookID {
+				result = append(result, job)
+			}
+		}
+
+		if len(jobs) < 100 {
+			return result
+		}
+	}
+}
+
+func jsonField(t *testing.T, data json.RawMessage, field string) string {
+	t.Helper()
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	return string(fields[field])
+}
+
+func testConcurrentCreateReception(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	pvz, err := s.CreatePVZ(ctx, model.Kazan)
+	require.NoError(t, err)
+
+	errs := parallel(20, func() error {
+		_, err := s.CreateReception(ctx, pvz.ID)
+		return err
+	})
+
+	successes := 0
+	for _, err := range errs {
+		if err == nil {
+			successes++
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrReceptionAlreadyExists)
+	}
+
+	assert.Equal(t, 1, successes, "exactly one reception may be opened")
+}
+
+func testConcurrentAddProduct(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	errs := parallel(20, func() error {
+		_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+		return err
+	})
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Len(t, got.Receptions[0].Products, 20)
+}
+
+func testConcurrentDeleteLastProduct(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	for i := 0; i < 5; i++ {
+		_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+		require.NoError(t, err)
+	}
+
+	errs := parallel(10, func() error {
+		return s.DeleteLastProduct(ctx, pvz.ID)
+	})
+
+	successes := 0
+	for _, err := range errs {
+		if err == nil {
+			successes++
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrNoProductsToDelete)
+	}
+
+	assert.Equal(t, 5, successes, "every product may be deleted exactly once")
+
+	got, err := s.FetchPVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Receptions[0].Products)
+}
+
+func testConcurrentDeleteProduct(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+	pvz, _ := newPVZWithReception(t, s)
+
+	product, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Electronics})
+	require.NoError(t, err)
+
+	errs := parallel(10, func() error {
+		_, err := s.DeleteProduct(ctx, pvz.ID, product.ID, "duplicate scan")
+		return err
+	})
+
+	successes := 0
+	for _, err := range errs {
+		if err == nil {
+			successes++
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrProductNotFound)
+	}
+
+	assert.Equal(t, 1, successes, "a product may be deleted exactly once")
+}
+
+// raceRounds repeats the close races to widen the window for interleavings.
+const raceRounds = 10
+
+// testRaceAddProductAndClose closes a reception while products are being
+// added. Whatever the reception holds right after close must never change,
+// and every successful AddProduct must be in it.
+func testRaceAddProductAndClose(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	for round := 0; round < raceRounds; round++ {
+		pvz, _ := newPVZWithReception(t, s)
+
+		errs, atClose := raceWithClose(t, s, pvz.ID, 10, func() error {
+			_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Clothing})
+			return err
+		})
+
+		added := 0
+		for _, err := range errs {
+			if err == nil {
+				added++
+				continue
+			}
+			assert.ErrorIs(t, err, store.ErrNoActiveReception)
+		}
+
+		got, err := s.FetchPVZ(ctx, pvz.ID)
+		require.NoError(t, err)
+		require.Len(t, got.Receptions, 1)
+		assert.Equal(t, model.Closed, got.Receptions[0].Reception.Status)
+		assert.Len(t, got.Receptions[0].Products, atClose, "products must not be added after close")
+		assert.Len(t, got.Receptions[0].Products, added)
+	}
+}
+
+// testRaceDeleteLastProductAndClose closes a reception while products are
+// being deleted from it. Nothing may be removed after close.
+func testRaceDeleteLastProductAndClose(t *testing.T, s store.Repository) {
+	ctx := context.Background()
+
+	for round := 0; round < raceRounds; round++ {
+		pvz, _ := newPVZWithReception(t, s)
+
+		for i := 0; i < 10; i++ {
+			_, err := s.AddProduct(ctx, pvz.ID, store.ProductDraft{Type: model.Shoes})
+			require.NoError(t, err)
+		}
+
+		errs, atClose := raceWithClose(t, s, pvz.ID, 10, func() error {
+			return s.DeleteLastProduct(ctx, pvz.ID)
+		})
+
+		deleted := 0
+		for _, err := range errs {
+			if err == nil {
+				deleted++
+				continue
+			}
+			assert.ErrorIs(t, err, store.ErrNoActiveReception)
+		}
+
+		got, err := s.FetchPVZ(ctx, pvz.ID)
+		require.NoError(t, err)
+		assert.Len(t, got.Receptions[0].Products, atClose, "products must not be deleted after close")
+		assert.Len(t, got.Receptions[0].Products, 10-deleted)
+	}
+}
+
+// raceWithClose runs n copies of fn together with CloseLastReception. It
+// returns the results of fn and how many products the reception held right
+// after the close returned.
+func raceWithClose(t *testing.T, s store.Repository, pvzID string, n int, fn func() error) ([]error, int) {
+	t.Helper()
+	ctx := context.Background()
+
+	var (
+		wg       sync.WaitGroup
+		closeErr error
+		atClose  int
+	)
+
+	start := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+
+		if _, closeErr = s.CloseLastReception(ctx, pvzID); closeErr != nil {
+			return
+		}
+
+		var got *model.PVZWithReceptions
+		if got, closeErr = s.FetchPVZ(ctx, pvzID); closeErr == nil {
+			atClose = len(got.Receptions[0].Products)
+		}
+	}()
+
+	errs := make([]error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn()
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	require.NoError(t, closeErr)
+
+	return errs, atClose
+}
+
+// parallel runs fn n times at once and returns every result.
+func parallel(n int, fn func() error) []error {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn()
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	return errs
+}
+
+func pvzOrdered(prev, next model.PVZ) bool {
+	if !prev.RegistrationDate.Equal(next.RegistrationDate) {
+		return prev.RegistrationDate.Before(next.RegistrationDate)
+	}
+	return prev.ID < next.ID
+}
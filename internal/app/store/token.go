@@ -0,0 +1,286 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateRefreshToken stores a refresh token. A token without a FamilyID
+// starts a new family. Expired refresh tokens are dropped first, they can
+// no longer be rotated or revoked.
+func (s *Store) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if token.FamilyID == "" {
+		token.FamilyID = uuid.NewString()
+	}
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at <= $1`, time.Now())
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	err = insertRefreshToken(ctx, s.db, token)
+
+	if isForeignKeyViolation(err) {
+		return ErrUserNotFound
+	}
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, tokenHash string, next *model.RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	var (
+		id, familyID, userID string
+		role                 model.UserRole
+		expiresAt            time.Time
+		usedAt, revokedAt    sql.NullTime
+	)
+
+	// The row lock makes concurrent rotations of one token line up, so only
+	// the first succeeds and the rest are treated as reuse.
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id, family_id, user_id, role, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`,
+		tokenHash,
+	).Scan(&id, &familyID, &userID, &role, &expiresAt, &usedAt, &revokedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefreshTokenInvalid
+	}
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if usedAt.Valid {
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return ErrDatabase
+		}
+
+		return ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+
+	if revokedAt.Valid || !expiresAt.After(now) {
+		return ErrRefreshTokenInvalid
+	}
+
+	// The role may have changed since login, the new pair carries the
+	// current one.
+	err = tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefreshTokenInvalid
+	}
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET used_at = $2 WHERE id = $1`,
+		id,
+		now,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	next.FamilyID = familyID
+	next.UserID = userID
+	next.Role = role
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return ErrDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes the family of a refresh token of the
+// user. A token of another user is treated as unknown.
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, userID, tokenHash string) error {
+	if uuid.Validate(userID) != nil {
+		return ErrRefreshTokenInvalid
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	var familyID string
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2`,
+		tokenHash,
+		userID,
+	).Scan(&familyID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefreshTokenInvalid
+	}
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if err := revokeFamily(ctx, tx, familyID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := pruneRevokedAccessTokens(ctx, s.db, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO revoked_access_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`,
+		jti,
+		expiresAt,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)`,
+		jti,
+	).Scan(&revoked)
+
+	if err != nil {
+		return false, ErrDatabase
+	}
+
+	return revoked, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *model.RefreshToken) error {
+	token.ID = uuid.NewString()
+	token.CreatedAt = time.Now()
+
+	_, err := db.ExecContext(
+		ctx,
+		`INSERT INTO refresh_tokens
+			(id, family_id, user_id, role, token_hash, access_jti, access_expires_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		token.ID,
+		token.FamilyID,
+		token.UserID,
+		token.Role,
+		token.TokenHash,
+		token.AccessJTI,
+		token.AccessExpiresAt,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+// pruneRevokedAccessTokens drops revocations of access tokens that have
+// expired, the tokens are rejected anyway.
+func pruneRevokedAccessTokens(ctx context.Context, db execer, now time.Time) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at <= $1`, now)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// revokeFamily revokes every refresh token of the family and the access
+// tokens issued with them that have not expired yet.
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	now := time.Now()
+
+	if err := pruneRevokedAccessTokens(ctx, tx, now); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET revoked_at = $2
+		WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID,
+		now,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO revoked_access_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens
+		WHERE family_id = $1 AND access_expires_at > $2
+		ON CONFLICT (jti) DO NOTHING`,
+		familyID,
+		now,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
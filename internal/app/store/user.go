@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pvz_server/internal/app/model"
+
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateUser(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+	if !model.AllowedUserRoles[role] {
+		return nil, ErrRoleNotAllowed
+	}
+
+	id := uuid.NewString()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO users (id, email, password_hash, role)
+		VALUES ($1, $2, $3, $4)`,
+		id,
+		email,
+		passwordHash,
+		role,
+	)
+
+	if isUniqueViolation(err) {
+		return nil, ErrUserAlreadyExists
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return &model.User{
+		ID:           id,
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var u model.User
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, email, password_hash, role FROM users
+		WHERE email = $1`,
+		email,
+	).Scan(
+		&u.ID,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Role,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,510 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"pvz_server/internal/app/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeliveryJob is a claimed delivery together with the subscription it is
+// sent to.
+type DeliveryJob struct {
+	Delivery model.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// DeliveryFailure describes a failed attempt. The delivery is retried at
+// RetryAt unless Dead is set.
+type DeliveryFailure struct {
+	StatusCode int
+	Error      string
+	RetryAt    time.Time
+	Dead       bool
+}
+
+// DeliveryFilter narrows ListWebhookDeliveries. An empty Status does not
+// filter.
+type DeliveryFilter struct {
+	Status model.DeliveryStatus
+	Page   int
+	Limit  int
+}
+
+// ValidateEventTypes rejects unknown event types and drops duplicates.
+func ValidateEventTypes(eventTypes []model.EventType) ([]model.EventType, error) {
+	seen := make(map[model.EventType]bool, len(eventTypes))
+	result := make([]model.EventType, 0, len(eventTypes))
+
+	for _, t := range eventTypes {
+		if !model.AllowedEventTypes[t] {
+			return nil, ErrEventTypeNotAllowed
+		}
+
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+
+	return result, nil
+}
+
+// DeliveryPayload is the request body of every delivery of ev.
+func DeliveryPayload(ev model.Event) (json.RawMessage, error) {
+	return json.Marshal(ev)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error) {
+	eventTypes, err := ValidateEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &model.WebhookSubscription{
+		ID:         uuid.NewString(),
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now(),
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO webhook_subscriptions (id, url, secret, event_types, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		w.ID,
+		w.URL,
+		w.Secret,
+		pq.Array(eventTypeStrings(w.EventTypes)),
+		w.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	return w, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]model.WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		ORDER BY created_at, id`,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	webhooks := []model.WebhookSubscription{}
+
+	for rows.Next() {
+		var (
+			w     model.WebhookSubscription
+			types []string
+		)
+
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, pq.Array(&types), &w.CreatedAt); err != nil {
+			return nil, ErrDatabase
+		}
+
+		for _, t := range types {
+			w.EventTypes = append(w.EventTypes, model.EventType(t))
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return webhooks, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrWebhookNotFound
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueWebhookDeliveries creates a pending delivery of ev for every
+// subscription to its type. Enqueueing an event again is a no-op, so the
+// outbox may publish it more than once.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, ev model.Event) error {
+	payload, err := DeliveryPayload(ev)
+	if err != nil {
+		return ErrDatabase
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id FROM webhook_subscriptions WHERE $1 = ANY(event_types)`,
+		ev.Type,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	var subscriptionIDs []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return ErrDatabase
+		}
+		subscriptionIDs = append(subscriptionIDs, id)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return ErrDatabase
+	}
+
+	now := time.Now()
+
+	for _, subscriptionID := range subscriptionIDs {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO webhook_deliveries
+				(id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $7)
+			ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+			uuid.NewString(),
+			subscriptionID,
+			ev.ID,
+			ev.Type,
+			[]byte(payload),
+			model.DeliveryPending,
+			now,
+		)
+
+		if err != nil {
+			return ErrDatabase
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+// due and hides them from other claims for the lease.
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DeliveryJob, error) {
+	now := time.Now()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`UPDATE webhook_deliveries d SET next_attempt_at = $1
+		FROM webhook_subscriptions w
+		WHERE w.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns+`, w.url, w.secret`,
+		now.Add(lease),
+		model.DeliveryPending,
+		now,
+		limit,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	jobs := []DeliveryJob{}
+
+	for rows.Next() {
+		var job DeliveryJob
+
+		if err := scanDelivery(rows, &job.Delivery, &job.URL, &job.Secret); err != nil {
+			return nil, ErrDatabase
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return jobs, nil
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id string, statusCode int) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = NULL, updated_at = $3
+		WHERE id = $4 AND status = $5`,
+		model.DeliverySucceeded,
+		statusCode,
+		time.Now(),
+		id,
+		model.DeliveryPending,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func (s *Store) FailWebhookDelivery(ctx context.Context, id string, failure DeliveryFailure) error {
+	status := model.DeliveryPending
+	if failure.Dead {
+		status = model.DeliveryDead
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, next_attempt_at = $2,
+			last_status_code = $3, last_error = $4, updated_at = $5
+		WHERE id = $6 AND status = $7`,
+		status,
+		failure.RetryAt,
+		sql.NullInt64{Int64: int64(failure.StatusCode), Valid: failure.StatusCode != 0},
+		failure.Error,
+		time.Now(),
+		id,
+		model.DeliveryPending,
+	)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns deliveries of a subscription, newest first.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, filter DeliveryFilter) ([]model.WebhookDelivery, error) {
+	if err := s.ensureWebhookExists(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $3 OFFSET $4`,
+		webhookID,
+		filter.Status,
+		filter.Limit,
+		(filter.Page-1)*filter.Limit,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+
+	for rows.Next() {
+		var d model.WebhookDelivery
+
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, ErrDatabase
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery puts a dead delivery back in the queue with a fresh
+// attempt budget.
+func (s *Store) ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	if uuid.Validate(deliveryID) != nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	if err := s.ensureWebhookExists(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	defer tx.Rollback()
+
+	var d model.WebhookDelivery
+
+	err = scanDelivery(tx.QueryRowContext(
+		ctx,
+		`SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.id = $1 AND d.subscription_id = $2
+		FOR UPDATE`,
+		deliveryID,
+		webhookID,
+	), &d)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if d.Status != model.DeliveryDead {
+		return nil, ErrDeliveryNotDead
+	}
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		SET status = $1, attempts = 0, next_attempt_at = $2, updated_at = $2
+		WHERE id = $3`,
+		model.DeliveryPending,
+		now,
+		d.ID,
+	)
+
+	if err != nil {
+		return nil, ErrDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrDatabase
+	}
+
+	d.Status = model.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+
+	return &d, nil
+}
+
+func (s *Store) ensureWebhookExists(ctx context.Context, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrWebhookNotFound
+	}
+
+	var exists bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`,
+		id,
+	).Scan(&exists)
+
+	if err != nil {
+		return ErrDatabase
+	}
+
+	if !exists {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status,
+	d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.updated_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDelivery reads deliveryColumns followed by extra.
+func scanDelivery(row scanner, d *model.WebhookDelivery, extra ...any) error {
+	var (
+		payload    []byte
+		statusCode sql.NullInt64
+		lastError  sql.NullString
+	)
+
+	dest := []any{
+		&d.ID,
+		&d.WebhookID,
+		&d.EventID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&statusCode,
+		&lastError,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	d.Payload = payload
+	d.LastStatusCode = int(statusCode.Int64)
+	d.LastError = lastError.String
+
+	return nil
+}
+
+func eventTypeStrings(types []model.EventType) []string {
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = string(t)
+	}
+	return result
+}
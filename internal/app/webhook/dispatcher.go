@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+)
+
+// Dispatcher is an outbox.Publisher that queues a delivery of every event
+// for each webhook subscribed to its type.
+type Dispatcher struct {
+	deliveries store.WebhookDeliveryEnqueuer
+}
+
+func NewDispatcher(deliveries store.WebhookDeliveryEnqueuer) *Dispatcher {
+	return &Dispatcher{deliveries: deliveries}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, ev model.Event) error {
+	return d.deliveries.EnqueueWebhookDeliveries(ctx, ev)
+}
@@ -0,0 +1,47 @@
+// Package webhook delivers outbox events to partner subscriptions. The
+// Dispatcher turns every published event into one delivery per subscribed
+// webhook and the Worker sends them, retrying failures with exponential
+// backoff until a delivery succeeds or is declared dead.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature header value of a request body sent at ts:
+// "sha256=" followed by the hex HMAC-SHA256 of "<unix ts>.<body>" keyed with
+// the subscription secret. The timestamp is signed so receivers can reject
+// replayed requests.
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time. Receivers
+// written in Go can use it as is.
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	expected := Sign(secret, time.Unix(unix, 0), body)
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,32 @@
+package webhook_test
+
+import (
+	"pvz_server/internal/app/webhook"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1746532800, 0)
+
+	// echo -n '1746532800.{"id":"e-1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t,
+		"sha256=64845b12363c13140fbbbcacd1480d7be631f75758807dd5bd41af2060e44153",
+		webhook.Sign("secret", ts, []byte(`{"id":"e-1"}`)))
+}
+
+func TestVerify(t *testing.T) {
+	ts := time.Now()
+	body := []byte(`{"id":"e-1"}`)
+	sig := webhook.Sign("secret", ts, body)
+	unix := strconv.FormatInt(ts.Unix(), 10)
+
+	assert.True(t, webhook.Verify("secret", unix, sig, body))
+	assert.False(t, webhook.Verify("other", unix, sig, body))
+	assert.False(t, webhook.Verify("secret", unix, sig, []byte(`{"id":"e-2"}`)))
+	assert.False(t, webhook.Verify("secret", strconv.FormatInt(ts.Unix()+1, 10), sig, body))
+	assert.False(t, webhook.Verify("secret", "now", sig, body))
+}
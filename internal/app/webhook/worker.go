@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"pvz_server/internal/app/metrics"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"time"
+)
+
+const (
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 50
+	DefaultLease       = time.Minute
+	DefaultMaxAttempts = 10
+
+	requestTimeout = 10 * time.Second
+
+	minBackoff = 5 * time.Second
+	maxBackoff = time.Hour
+)
+
+// Worker sends due deliveries. Each attempt is a POST of the event JSON with
+// the X-Event-ID, X-Event-Type, X-Webhook-Timestamp and X-Webhook-Signature
+// headers; any 2xx response completes the delivery.
+type Worker struct {
+	queue  store.WebhookDeliveryQueue
+	client *http.Client
+
+	Interval    time.Duration
+	BatchSize   int
+	Lease       time.Duration
+	MaxAttempts int
+}
+
+// NewWorker uses client for requests, or a client with a 10s timeout when
+// client is nil.
+func NewWorker(queue store.WebhookDeliveryQueue, client *http.Client) *Worker {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &Worker{
+		queue:       queue,
+		client:      client,
+		Interval:    DefaultInterval,
+		BatchSize:   DefaultBatchSize,
+		Lease:       DefaultLease,
+		MaxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// Run sends deliveries every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := w.ProcessBatch(ctx)
+
+		if err != nil {
+			log.Printf("webhook worker: %v", err)
+		}
+
+		if err == nil && n == w.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch sends one batch of due deliveries and returns how many were
+// claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	jobs, err := w.queue.ClaimWebhookDeliveries(ctx, w.BatchSize, w.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		if err := w.deliver(ctx, job); err != nil {
+			return len(jobs), err
+		}
+	}
+
+	return len(jobs), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, job store.DeliveryJob) error {
+	d := job.Delivery
+
+	statusCode, err := w.send(ctx, job)
+
+	if err == nil {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(string(model.DeliverySucceeded)).Inc()
+		return w.queue.CompleteWebhookDelivery(ctx, d.ID, statusCode)
+	}
+
+	attempts := d.Attempts + 1
+
+	failure := store.DeliveryFailure{
+		StatusCode: statusCode,
+		Error:      err.Error(),
+		RetryAt:    time.Now().Add(Backoff(attempts)),
+		Dead:       attempts >= w.MaxAttempts,
+	}
+
+	if failure.Dead {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(string(model.DeliveryDead)).Inc()
+	} else {
+		metrics.WebhookDeliveriesTotal.WithLabelValues("retry").Inc()
+	}
+
+	return w.queue.FailWebhookDelivery(ctx, d.ID, failure)
+}
+
+// send returns the response status, or 0 when no response was received.
+func (w *Worker) send(ctx context.Context, job store.DeliveryJob) (int, error) {
+	d := job.Delivery
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", d.EventID)
+	req.Header.Set("X-Event-Type", string(d.EventType))
+	req.Header.Set(TimestampHeader, fmt.Sprint(now.Unix()))
+	req.Header.Set(SignatureHeader, Sign(job.Secret, now, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the delay before the next attempt of a delivery that
+// failed attempts times: five seconds doubling up to an hour.
+func Backoff(attempts int) time.Duration {
+	d := minBackoff
+
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, maxBackoff)
+}
@@ -0,0 +1,150 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/memory"
+	"pvz_server/internal/app/webhook"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef"
+
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	w.WriteHeader(rc.status)
+}
+
+func setup(t *testing.T, status int) (*memory.Store, *receiver, *model.WebhookSubscription) {
+	t.Helper()
+
+	rc := &receiver{status: status}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	s := memory.New()
+	w, err := s.CreateWebhook(context.Background(), srv.URL, testSecret, []model.EventType{model.EventReceptionClosed})
+	require.NoError(t, err)
+
+	for _, eventType := range []model.EventType{model.EventReceptionOpened, model.EventReceptionClosed} {
+		ev, err := store.NewEvent(eventType, uuid.NewString(), map[string]string{"status": "close"}, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, webhook.NewDispatcher(s).Publish(context.Background(), *ev))
+	}
+
+	return s, rc, w
+}
+
+func deliveries(t *testing.T, s *memory.Store, webhookID string) []model.WebhookDelivery {
+	t.Helper()
+
+	result, err := s.ListWebhookDeliveries(context.Background(), webhookID, store.DeliveryFilter{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	return result
+}
+
+func TestWorker_DeliversSignedEvents(t *testing.T) {
+	s, rc, w := setup(t, http.StatusNoContent)
+
+	n, err := webhook.NewWorker(s, nil).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only subscribed event types are delivered")
+	require.Len(t, rc.requests, 1)
+
+	req := rc.requests[0]
+	assert.Equal(t, string(model.EventReceptionClosed), req.Header.Get("X-Event-Type"))
+	assert.True(t, webhook.Verify(testSecret, req.Header.Get(webhook.TimestampHeader), req.Header.Get(webhook.SignatureHeader), rc.bodies[0]))
+
+	got := deliveries(t, s, w.ID)
+	require.Len(t, got, 1)
+	assert.Equal(t, model.DeliverySucceeded, got[0].Status)
+	assert.Equal(t, req.Header.Get("X-Event-ID"), got[0].EventID)
+	assert.JSONEq(t, string(got[0].Payload), string(rc.bodies[0]))
+}
+
+func TestWorker_RetriesAndDeadLetters(t *testing.T) {
+	s, rc, w := setup(t, http.StatusServiceUnavailable)
+
+	worker := webhook.NewWorker(s, nil)
+	worker.MaxAttempts = 2
+
+	_, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	got := deliveries(t, s, w.ID)[0]
+	assert.Equal(t, model.DeliveryPending, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, got.LastStatusCode)
+	assert.WithinDuration(t, time.Now().Add(webhook.Backoff(1)), got.NextAttemptAt, time.Second)
+
+	// Not due yet.
+	n, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = s.ReplayWebhookDelivery(context.Background(), w.ID, got.ID)
+	assert.ErrorIs(t, err, store.ErrDeliveryNotDead)
+
+	// Make the delivery due right away; this records a second attempt.
+	require.NoError(t, s.FailWebhookDelivery(context.Background(), got.ID, store.DeliveryFailure{
+		StatusCode: got.LastStatusCode,
+		Error:      got.LastError,
+		RetryAt:    time.Now(),
+	}))
+
+	_, err = worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	got = deliveries(t, s, w.ID)[0]
+	assert.Equal(t, model.DeliveryDead, got.Status)
+	assert.Equal(t, 3, got.Attempts)
+	assert.Len(t, rc.requests, 2)
+}
+
+func TestWorker_ConnectionError(t *testing.T) {
+	s := memory.New()
+	w, err := s.CreateWebhook(context.Background(), "http://127.0.0.1:1", testSecret, []model.EventType{model.EventProductAdded})
+	require.NoError(t, err)
+
+	ev, err := store.NewEvent(model.EventProductAdded, uuid.NewString(), map[string]string{}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, s.EnqueueWebhookDeliveries(context.Background(), *ev))
+
+	_, err = webhook.NewWorker(s, nil).ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	got := deliveries(t, s, w.ID)[0]
+	assert.Equal(t, model.DeliveryPending, got.Status)
+	assert.Zero(t, got.LastStatusCode)
+	assert.NotEmpty(t, got.LastError)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, webhook.Backoff(1))
+	assert.Equal(t, 40*time.Second, webhook.Backoff(4))
+	assert.Equal(t, time.Hour, webhook.Backoff(30))
+}
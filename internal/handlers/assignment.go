@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AssignmentInput struct {
+	PVZID string `json:"pvzId" binding:"required"`
+}
+
+func AssignPVZ(storeInst store.PVZAssigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userId")
+
+		if uuid.Validate(userID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidUserID)
+			return
+		}
+
+		var req AssignmentInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		if uuid.Validate(req.PVZID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		if err := storeInst.AssignPVZ(c.Request.Context(), userID, req.PVZID); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, model.PVZAssignment{UserID: userID, PVZID: req.PVZID})
+	}
+}
+
+func UnassignPVZ(storeInst store.PVZAssigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userId")
+
+		if uuid.Validate(userID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidUserID)
+			return
+		}
+
+		pvzID := c.Param("pvzId")
+
+		if uuid.Validate(pvzID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		if err := storeInst.UnassignPVZ(c.Request.Context(), userID, pvzID); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func ListAssignedPVZs(storeInst store.PVZAssigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userId")
+
+		if uuid.Validate(userID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidUserID)
+			return
+		}
+
+		pvzs, err := storeInst.ListAssignedPVZs(c.Request.Context(), userID)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pvzs)
+	}
+}
+
+// ensureAssigned rejects callers acting on a PVZ they are not assigned to,
+// or on a PVZ that does not exist. Assignments are looked up on every
+// request, so unassigning an employee takes effect without waiting for their
+// token to expire.
+func ensureAssigned(c *gin.Context, assignments store.AssignmentChecker, pvzID string) bool {
+	assigned, err := assignments.IsAssigned(c.Request.Context(), c.GetString("userID"), pvzID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return false
+	}
+
+	if !assigned {
+		apierror.Respond(c, apierror.ErrPVZNotAssigned)
+		return false
+	}
+
+	return true
+}
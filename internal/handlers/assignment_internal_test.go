@@ -0,0 +1,234 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testUserID = "0d4f8a8e-6a55-4f0e-8d59-2f7d0b8e5c21"
+
+type mockAssignments struct {
+	pvzIDs map[string]bool
+	err    error
+}
+
+func (m *mockAssignments) IsAssigned(ctx context.Context, userID, pvzID string) (bool, error) {
+	return m.pvzIDs[pvzID], m.err
+}
+
+func assignedTo(pvzIDs ...string) *mockAssignments {
+	m := &mockAssignments{pvzIDs: make(map[string]bool)}
+	for _, id := range pvzIDs {
+		m.pvzIDs[id] = true
+	}
+	return m
+}
+
+type mockAssigner struct {
+	assignFunc   func(ctx context.Context, userID, pvzID string) error
+	unassignFunc func(ctx context.Context, userID, pvzID string) error
+	listFunc     func(ctx context.Context, userID string) ([]model.PVZ, error)
+}
+
+func (m *mockAssigner) AssignPVZ(ctx context.Context, userID, pvzID string) error {
+	return m.assignFunc(ctx, userID, pvzID)
+}
+
+func (m *mockAssigner) UnassignPVZ(ctx context.Context, userID, pvzID string) error {
+	return m.unassignFunc(ctx, userID, pvzID)
+}
+
+func (m *mockAssigner) ListAssignedPVZs(ctx context.Context, userID string) ([]model.PVZ, error) {
+	return m.listFunc(ctx, userID)
+}
+
+func setupAssignmentRouter(role string, store *mockAssigner) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.Use(func(c *gin.Context) {
+		c.Set("role", role)
+		c.Next()
+	})
+
+	r.GET("/users/:userId/pvz", handlers.ListAssignedPVZs(store))
+	r.POST("/users/:userId/pvz", handlers.AssignPVZ(store))
+	r.DELETE("/users/:userId/pvz/:pvzId", handlers.UnassignPVZ(store))
+	return r
+}
+
+func TestAssignPVZ_Success(t *testing.T) {
+	var gotUser, gotPVZ string
+	mock := &mockAssigner{
+		assignFunc: func(ctx context.Context, userID, pvzID string) error {
+			gotUser, gotPVZ = userID, pvzID
+			return nil
+		},
+	}
+	router := setupAssignmentRouter("moderator", mock)
+
+	body, _ := json.Marshal(map[string]string{"pvzId": testPVZID})
+	req, _ := http.NewRequest("POST", "/users/"+testUserID+"/pvz", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, testUserID, gotUser)
+	assert.Equal(t, testPVZID, gotPVZ)
+}
+
+func TestAssignPVZ_NotEmployee(t *testing.T) {
+	mock := &mockAssigner{
+		assignFunc: func(ctx context.Context, userID, pvzID string) error {
+			return store.ErrUserNotEmployee
+		},
+	}
+	router := setupAssignmentRouter("moderator", mock)
+
+	body, _ := json.Marshal(map[string]string{"pvzId": testPVZID})
+	req, _ := http.NewRequest("POST", "/users/"+testUserID+"/pvz", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "user_not_employee")
+}
+
+func TestAssignPVZ_InvalidUserID(t *testing.T) {
+	router := setupAssignmentRouter("moderator", &mockAssigner{})
+
+	body, _ := json.Marshal(map[string]string{"pvzId": testPVZID})
+	req, _ := http.NewRequest("POST", "/users/u1/pvz", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid user ID")
+}
+
+func TestUnassignPVZ_NotFound(t *testing.T) {
+	mock := &mockAssigner{
+		unassignFunc: func(ctx context.Context, userID, pvzID string) error {
+			return store.ErrAssignmentNotFound
+		},
+	}
+	router := setupAssignmentRouter("moderator", mock)
+
+	req, _ := http.NewRequest("DELETE", "/users/"+testUserID+"/pvz/"+testPVZID, nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestListAssignedPVZs_Success(t *testing.T) {
+	mock := &mockAssigner{
+		listFunc: func(ctx context.Context, userID string) ([]model.PVZ, error) {
+			return []model.PVZ{{ID: testPVZID, RegistrationDate: time.Now(), City: model.Moscow}}, nil
+		},
+	}
+	router := setupAssignmentRouter("moderator", mock)
+
+	req, _ := http.NewRequest("GET", "/users/"+testUserID+"/pvz", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), testPVZID)
+}
+
+// setupUnassignedRouter wires the mutating endpoints for an employee the
+// assignment check rejects; none of them may reach the store.
+func setupUnassignedRouter(t *testing.T, assignments store.AssignmentChecker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.Use(func(c *gin.Context) {
+		c.Set("role", "employee")
+		c.Set("userID", testUserID)
+		c.Next()
+	})
+
+	fail := func(ctx context.Context, pvzID string) (*model.Reception, error) {
+		t.Error("store must not be called")
+		return nil, nil
+	}
+	receptions := &mockReceptionStore{createFunc: fail, closeFunc: fail}
+	products := &mockProductStore{
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+			t.Error("store must not be called")
+			return nil, nil
+		},
+		deleteFunc: func(ctx context.Context, pvzID string) error {
+			t.Error("store must not be called")
+			return nil
+		},
+	}
+
+	r.POST("/receptions", handlers.CreateReception(receptions, assignments))
+	r.POST("/pvz/:pvzId/close_last_reception", handlers.CloseLastReception(receptions, assignments))
+	r.POST("/products", handlers.AddProduct(products, assignments))
+	r.POST("/pvz/:pvzId/delete_last_product", handlers.DeleteLastProduct(products, assignments))
+	return r
+}
+
+var mutationRequests = []struct {
+	path string
+	body map[string]string
+}{
+	{"/receptions", map[string]string{"pvzId": testPVZID}},
+	{"/pvz/" + testPVZID + "/close_last_reception", nil},
+	{"/products", map[string]string{"pvzId": testPVZID, "type": "обувь"}},
+	{"/pvz/" + testPVZID + "/delete_last_product", nil},
+}
+
+func TestMutations_PVZNotAssigned(t *testing.T) {
+	router := setupUnassignedRouter(t, assignedTo())
+
+	for _, tt := range mutationRequests {
+		t.Run(tt.path, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "pvz_not_assigned")
+		})
+	}
+}
+
+// An unknown PVZ is reported as missing rather than as not assigned.
+func TestMutations_PVZNotFound(t *testing.T) {
+	router := setupUnassignedRouter(t, &mockAssignments{err: store.ErrPVZNotFound})
+
+	for _, tt := range mutationRequests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(router, "POST", tt.path, tt.body)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Contains(t, w.Body.String(), "pvz_not_found")
+		})
+	}
+}
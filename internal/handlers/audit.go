@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/store"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidFrom = apierror.New(http.StatusBadRequest, "invalid_from", "invalid from")
+	errInvalidTo   = apierror.New(http.StatusBadRequest, "invalid_to", "invalid to")
+)
+
+// GetAuditEvents lists audit events newest first, optionally narrowed to a
+// PVZ, an actor and a time range.
+func GetAuditEvents(storeInst store.AuditFetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := store.AuditFilter{
+			PVZID:   c.Query("pvzId"),
+			ActorID: c.Query("actorId"),
+		}
+
+		if filter.PVZID != "" && uuid.Validate(filter.PVZID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		if fromStr := c.Query("from"); fromStr != "" {
+			t, err := time.Parse(time.RFC3339, fromStr)
+			if err != nil {
+				apierror.Respond(c, errInvalidFrom)
+				return
+			}
+			filter.From = &t
+		}
+
+		if toStr := c.Query("to"); toStr != "" {
+			t, err := time.Parse(time.RFC3339, toStr)
+			if err != nil {
+				apierror.Respond(c, errInvalidTo)
+				return
+			}
+			filter.To = &t
+		}
+
+		filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+		filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+		if filter.Page < 1 || filter.Limit < 1 || filter.Limit > 100 {
+			apierror.Respond(c, errInvalidPagination)
+			return
+		}
+
+		events, err := storeInst.FetchAuditEvents(c.Request.Context(), filter)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, events)
+	}
+}
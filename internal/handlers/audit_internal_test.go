@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAuditStore struct {
+	fetchFunc func(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error)
+}
+
+func (m *mockAuditStore) FetchAuditEvents(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error) {
+	return m.fetchFunc(ctx, filter)
+}
+
+func getAudit(mock *mockAuditStore, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/audit", handlers.GetAuditEvents(mock))
+
+	req, _ := http.NewRequest("GET", "/audit"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetAuditEvents_Success(t *testing.T) {
+	var got store.AuditFilter
+	mock := &mockAuditStore{
+		fetchFunc: func(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error) {
+			got = filter
+			return []model.AuditEvent{{ID: "e-1", Action: model.AuditPVZCreated, PVZID: testPVZID}}, nil
+		},
+	}
+
+	w := getAudit(mock, "?pvzId="+testPVZID+"&actorId="+testUserID+
+		"&from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&page=2&limit=10")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"action":"pvz.created"`)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, store.AuditFilter{
+		PVZID:   testPVZID,
+		ActorID: testUserID,
+		From:    &from,
+		To:      &to,
+		Page:    2,
+		Limit:   10,
+	}, got)
+}
+
+func TestGetAuditEvents_Defaults(t *testing.T) {
+	var got store.AuditFilter
+	mock := &mockAuditStore{
+		fetchFunc: func(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error) {
+			got = filter
+			return []model.AuditEvent{}, nil
+		},
+	}
+
+	w := getAudit(mock, "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	assert.Equal(t, store.AuditFilter{Page: 1, Limit: 50}, got)
+}
+
+func TestGetAuditEvents_InvalidQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{"?pvzId=pvz1", "invalid_pvz_id"},
+		{"?from=yesterday", "invalid_from"},
+		{"?to=2025-01-01", "invalid_to"},
+		{"?page=0", "invalid_pagination"},
+		{"?limit=101", "invalid_pagination"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := getAudit(&mockAuditStore{}, tt.query)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestGetAuditEvents_DatabaseError(t *testing.T) {
+	mock := &mockAuditStore{
+		fetchFunc: func(ctx context.Context, filter store.AuditFilter) ([]model.AuditEvent, error) {
+			return nil, store.ErrDatabase
+		},
+	}
+
+	w := getAudit(mock, "")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -1,29 +1,253 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
 	"pvz_server/internal/utils"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type dummyLoginRequest struct {
-	Role string `json:"role" binding:"required,oneof=employee moderator"`
+	Role string `json:"role" binding:"required,oneof=employee moderator admin auditor"`
 }
 
-func DummyLogin(c *gin.Context) {
-	var req dummyLoginRequest
+// RegisterInput is a self-registration. It always creates an employee; the
+// role may be omitted or set to employee for older clients.
+type RegisterInput struct {
+	Email    string         `json:"email" binding:"required,email"`
+	Password string         `json:"password" binding:"required,min=8,max=72"`
+	Role     model.UserRole `json:"role" binding:"omitempty,oneof=employee"`
+}
+
+// CreateUserInput creates a user with any role on behalf of an
+// authenticated caller holding user:create.
+type CreateUserInput struct {
+	Email    string         `json:"email" binding:"required,email"`
+	Password string         `json:"password" binding:"required,min=8,max=72"`
+	Role     model.UserRole `json:"role" binding:"required,oneof=employee moderator admin auditor"`
+}
+
+type LoginInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LoginStore interface {
+	store.UserFetcher
+	store.RefreshTokenCreator
+}
+
+// DummyLogin issues a token for an arbitrary role without credentials.
+// It is only registered when the server runs in dev mode.
+func DummyLogin(keys *jwtkeys.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dummyLoginRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		token, err := utils.GenerateJWT(keys, utils.NewAccessToken(uuid.NewString(), req.Role))
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token})
+	}
+}
+
+// Register is the public self-registration; it only creates employees.
+// Other roles are created through CreateUser.
+func Register(storeInst store.UserCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RegisterInput
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request"})
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		createUser(c, storeInst, req.Email, req.Password, model.Employee)
+	}
+}
+
+func CreateUser(storeInst store.UserCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateUserInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		createUser(c, storeInst, req.Email, req.Password, req.Role)
+	}
+}
+
+func createUser(c *gin.Context, storeInst store.UserCreator, email, password string, role model.UserRole) {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	token, err := utils.GenerateJWT(req.Role)
+	user, err := storeInst.CreateUser(c.Request.Context(), normalizeEmail(email), hash, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to generate token"})
+		apierror.Respond(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusCreated, user)
+}
+
+func Login(storeInst LoginStore, keys *jwtkeys.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		user, err := storeInst.GetUserByEmail(c.Request.Context(), normalizeEmail(req.Email))
+
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			apierror.Respond(c, apierror.ErrInvalidCredentials)
+			return
+		case err != nil:
+			apierror.Respond(c, err)
+			return
+		}
+
+		if !utils.CheckPassword(user.PasswordHash, req.Password) {
+			apierror.Respond(c, apierror.ErrInvalidCredentials)
+			return
+		}
+
+		access := utils.NewAccessToken(user.ID, string(user.Role))
+
+		refresh, next, err := newRefreshToken(access)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		next.UserID = user.ID
+		next.Role = user.Role
+
+		if err := storeInst.CreateRefreshToken(c.Request.Context(), next); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		respondTokenPair(c, keys, access, refresh)
+	}
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented
+// refresh token is spent; presenting it again revokes every token issued
+// from the same login.
+func Refresh(storeInst store.RefreshTokenRotator, keys *jwtkeys.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		// User and role are only known after rotation, the access token
+		// ID and expiry are fixed up front so they are stored with the family.
+		access := utils.NewAccessToken("", "")
+
+		refresh, next, err := newRefreshToken(access)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		if err := storeInst.RotateRefreshToken(c.Request.Context(), utils.HashRefreshToken(req.RefreshToken), next); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		access.UserID = next.UserID
+		access.Role = string(next.Role)
+
+		respondTokenPair(c, keys, access, refresh)
+	}
+}
+
+// Logout revokes the presented refresh token family and the access token
+// the request was made with. Only the caller's own refresh tokens are
+// accepted.
+func Logout(storeInst store.TokenRevoker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		if err := storeInst.RevokeRefreshTokenFamily(c.Request.Context(), c.GetString("userID"), utils.HashRefreshToken(req.RefreshToken)); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		if err := storeInst.RevokeAccessToken(c.Request.Context(), c.GetString("jti"), c.GetTime("tokenExpiresAt")); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func newRefreshToken(access utils.AccessToken) (string, *model.RefreshToken, error) {
+	refresh, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return refresh, &model.RefreshToken{
+		TokenHash:       hash,
+		AccessJTI:       access.JTI,
+		AccessExpiresAt: access.ExpiresAt,
+		ExpiresAt:       time.Now().Add(utils.RefreshTokenTTL),
+	}, nil
+}
+
+func respondTokenPair(c *gin.Context, keys *jwtkeys.Manager, access utils.AccessToken, refresh string) {
+	token, err := utils.GenerateJWT(keys, access)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenPair{Token: token, RefreshToken: refresh})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
@@ -2,11 +2,17 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
 	"pvz_server/internal/handlers"
+	"pvz_server/internal/utils"
+	"time"
 
 	"testing"
 
@@ -14,13 +20,21 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var testKeys = newTestKeys()
+
+func newTestKeys() *jwtkeys.Manager {
+	key, _ := jwtkeys.NewHMACKey("test", []byte("test-secret"))
+	keys, _ := jwtkeys.NewManager("test", key)
+	return keys
+}
+
 func TestDummyLogin_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	gin.DefaultWriter = io.Discard
 	gin.DefaultErrorWriter = io.Discard
 
 	router := gin.Default()
-	router.POST("/dummyLogin", handlers.DummyLogin)
+	router.POST("/dummyLogin", handlers.DummyLogin(testKeys))
 
 	payload := map[string]string{"role": "moderator"}
 	body, _ := json.Marshal(payload)
@@ -46,7 +60,7 @@ func TestDummyLogin_InvalidRole(t *testing.T) {
 	gin.DefaultErrorWriter = io.Discard
 
 	router := gin.Default()
-	router.POST("/dummyLogin", handlers.DummyLogin)
+	router.POST("/dummyLogin", handlers.DummyLogin(testKeys))
 
 	payload := map[string]string{"role": "test"}
 	body, _ := json.Marshal(payload)
@@ -66,7 +80,7 @@ func TestDummyLogin_EmptyBody(t *testing.T) {
 	gin.DefaultErrorWriter = io.Discard
 
 	router := gin.Default()
-	router.POST("/dummyLogin", handlers.DummyLogin)
+	router.POST("/dummyLogin", handlers.DummyLogin(testKeys))
 
 	req, _ := http.NewRequest("POST", "/dummyLogin", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -76,3 +90,355 @@ func TestDummyLogin_EmptyBody(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
+
+type mockUserStore struct {
+	createFunc func(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error)
+	getFunc    func(ctx context.Context, email string) (*model.User, error)
+
+	refreshTokens []*model.RefreshToken
+}
+
+func (m *mockUserStore) CreateUser(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+	return m.createFunc(ctx, email, passwordHash, role)
+}
+
+func (m *mockUserStore) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return m.getFunc(ctx, email)
+}
+
+func (m *mockUserStore) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	m.refreshTokens = append(m.refreshTokens, token)
+	return nil
+}
+
+func setupAuthRouter(store *mockUserStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	gin.DefaultErrorWriter = io.Discard
+
+	router := gin.Default()
+	router.POST("/register", handlers.Register(store))
+	router.POST("/login", handlers.Login(store, testKeys))
+	return router
+}
+
+func TestRegister_Success(t *testing.T) {
+	var storedHash string
+	mock := &mockUserStore{
+		createFunc: func(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+			storedHash = passwordHash
+			return &model.User{ID: "u-1", Email: email, PasswordHash: passwordHash, Role: role}, nil
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	payload := map[string]string{"email": "Worker@Example.com", "password": "password123", "role": "employee"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"email":"worker@example.com"`)
+	assert.NotContains(t, resp.Body.String(), "password")
+	assert.True(t, utils.CheckPassword(storedHash, "password123"))
+}
+
+func TestRegister_InvalidEmail(t *testing.T) {
+	router := setupAuthRouter(&mockUserStore{})
+
+	payload := map[string]string{"email": "not-an-email", "password": "password123", "role": "employee"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid request")
+}
+
+func TestRegister_UserAlreadyExists(t *testing.T) {
+	mock := &mockUserStore{
+		createFunc: func(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+			return nil, store.ErrUserAlreadyExists
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	payload := map[string]string{"email": "worker@example.com", "password": "password123", "role": "employee"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Contains(t, resp.Body.String(), "user already exists")
+}
+
+func TestRegister_AlwaysEmployee(t *testing.T) {
+	var roles []model.UserRole
+	mock := &mockUserStore{
+		createFunc: func(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+			roles = append(roles, role)
+			return &model.User{ID: "u-1", Email: email, Role: role}, nil
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	w := serve(router, "POST", "/register", map[string]string{"email": "worker@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	for _, role := range []string{"moderator", "admin", "auditor"} {
+		t.Run(role, func(t *testing.T) {
+			w := serve(router, "POST", "/register", map[string]string{"email": "boss@example.com", "password": "password123", "role": role})
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"field":"role"`)
+		})
+	}
+
+	assert.Equal(t, []model.UserRole{model.Employee}, roles, "privileged users are never created anonymously")
+}
+
+func TestCreateUser(t *testing.T) {
+	var got model.UserRole
+	mock := &mockUserStore{
+		createFunc: func(ctx context.Context, email, passwordHash string, role model.UserRole) (*model.User, error) {
+			got = role
+			return &model.User{ID: "u-1", Email: email, Role: role}, nil
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/users", handlers.CreateUser(mock))
+
+	w := serve(r, "POST", "/users", map[string]string{"email": "Boss@Example.com", "password": "password123", "role": "moderator"})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, model.Moderator, got)
+	assert.Contains(t, w.Body.String(), `"email":"boss@example.com"`)
+
+	w = serve(r, "POST", "/users", map[string]string{"email": "audit@example.com", "password": "password123", "role": "auditor"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, model.Auditor, got)
+
+	w = serve(r, "POST", "/users", map[string]string{"email": "boss@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLogin_Success(t *testing.T) {
+	hash, _ := utils.HashPassword("password123")
+	mock := &mockUserStore{
+		getFunc: func(ctx context.Context, email string) (*model.User, error) {
+			return &model.User{ID: "u-1", Email: email, PasswordHash: hash, Role: model.Employee}, nil
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	payload := map[string]string{"email": "worker@example.com", "password": "password123"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result handlers.TokenPair
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+
+	if assert.Len(t, mock.refreshTokens, 1) {
+		stored := mock.refreshTokens[0]
+		assert.Equal(t, "u-1", stored.UserID)
+		assert.Equal(t, model.Employee, stored.Role)
+		assert.Equal(t, utils.HashRefreshToken(result.RefreshToken), stored.TokenHash)
+		assert.NotEmpty(t, stored.AccessJTI)
+	}
+}
+
+func TestLogin_WrongPassword(t *testing.T) {
+	hash, _ := utils.HashPassword("password123")
+	mock := &mockUserStore{
+		getFunc: func(ctx context.Context, email string) (*model.User, error) {
+			return &model.User{ID: "u-1", Email: email, PasswordHash: hash, Role: model.Employee}, nil
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	payload := map[string]string{"email": "worker@example.com", "password": "wrong-password"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid credentials")
+}
+
+func TestLogin_UnknownUser(t *testing.T) {
+	mock := &mockUserStore{
+		getFunc: func(ctx context.Context, email string) (*model.User, error) {
+			return nil, store.ErrUserNotFound
+		},
+	}
+	router := setupAuthRouter(mock)
+
+	payload := map[string]string{"email": "nobody@example.com", "password": "password123"}
+	body, _ := json.Marshal(payload)
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid credentials")
+}
+
+type mockTokenStore struct {
+	rotateFunc       func(ctx context.Context, tokenHash string, next *model.RefreshToken) error
+	revokeFamilyFunc func(ctx context.Context, userID, tokenHash string) error
+
+	revokedJTIs []string
+}
+
+func (m *mockTokenStore) RotateRefreshToken(ctx context.Context, tokenHash string, next *model.RefreshToken) error {
+	return m.rotateFunc(ctx, tokenHash, next)
+}
+
+func (m *mockTokenStore) RevokeRefreshTokenFamily(ctx context.Context, userID, tokenHash string) error {
+	return m.revokeFamilyFunc(ctx, userID, tokenHash)
+}
+
+func (m *mockTokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.revokedJTIs = append(m.revokedJTIs, jti)
+	return nil
+}
+
+func setupTokenRouter(store *mockTokenStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	gin.DefaultErrorWriter = io.Discard
+
+	router := gin.Default()
+	router.POST("/auth/refresh", handlers.Refresh(store, testKeys))
+	router.POST("/auth/logout", func(c *gin.Context) {
+		c.Set("userID", testUserID)
+		c.Set("jti", "access-jti")
+		c.Set("tokenExpiresAt", time.Now().Add(time.Minute))
+	}, handlers.Logout(store))
+	return router
+}
+
+func postRefreshToken(router *gin.Engine, path, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"refreshToken": refreshToken})
+
+	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRefresh_Success(t *testing.T) {
+	var rotatedHash string
+	var next *model.RefreshToken
+	mock := &mockTokenStore{
+		rotateFunc: func(ctx context.Context, tokenHash string, n *model.RefreshToken) error {
+			rotatedHash = tokenHash
+			n.UserID = "u-1"
+			n.Role = model.Employee
+			next = n
+			return nil
+		},
+	}
+	router := setupTokenRouter(mock)
+
+	resp := postRefreshToken(router, "/auth/refresh", "old-token")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result handlers.TokenPair
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.Equal(t, utils.HashRefreshToken("old-token"), rotatedHash)
+	assert.Equal(t, utils.HashRefreshToken(result.RefreshToken), next.TokenHash)
+	assert.NotEmpty(t, next.AccessJTI)
+}
+
+func TestRefresh_Reused(t *testing.T) {
+	mock := &mockTokenStore{
+		rotateFunc: func(ctx context.Context, tokenHash string, next *model.RefreshToken) error {
+			return store.ErrRefreshTokenReused
+		},
+	}
+	router := setupTokenRouter(mock)
+
+	resp := postRefreshToken(router, "/auth/refresh", "old-token")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "refresh_token_reused")
+}
+
+func TestRefresh_MissingToken(t *testing.T) {
+	router := setupTokenRouter(&mockTokenStore{})
+
+	resp := postRefreshToken(router, "/auth/refresh", "")
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestLogout_Success(t *testing.T) {
+	var revokedUser, revokedHash string
+	mock := &mockTokenStore{
+		revokeFamilyFunc: func(ctx context.Context, userID, tokenHash string) error {
+			revokedUser, revokedHash = userID, tokenHash
+			return nil
+		},
+	}
+	router := setupTokenRouter(mock)
+
+	resp := postRefreshToken(router, "/auth/logout", "refresh-token")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, testUserID, revokedUser, "only the caller's own tokens are revoked")
+	assert.Equal(t, utils.HashRefreshToken("refresh-token"), revokedHash)
+	assert.Equal(t, []string{"access-jti"}, mock.revokedJTIs)
+}
+
+func TestLogout_UnknownToken(t *testing.T) {
+	mock := &mockTokenStore{
+		revokeFamilyFunc: func(ctx context.Context, userID, tokenHash string) error {
+			return store.ErrRefreshTokenInvalid
+		},
+	}
+	router := setupTokenRouter(mock)
+
+	resp := postRefreshToken(router, "/auth/logout", "refresh-token")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Empty(t, mock.revokedJTIs)
+}
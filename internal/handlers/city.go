@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidCityID          = apierror.New(http.StatusBadRequest, "invalid_city_id", "invalid city ID")
+	errInvalidCityName        = apierror.New(http.StatusBadRequest, "invalid_city_name", "city name must not be blank")
+	errInvalidIncludeInactive = apierror.New(http.StatusBadRequest, "invalid_include_inactive", "invalid includeInactive")
+)
+
+type CityInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// ListCities lists active cities, and inactive ones with
+// includeInactive=true.
+func ListCities(storeInst store.CityManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		includeInactive, err := strconv.ParseBool(c.DefaultQuery("includeInactive", "false"))
+		if err != nil {
+			apierror.Respond(c, errInvalidIncludeInactive)
+			return
+		}
+
+		cities, err := storeInst.ListCities(c.Request.Context(), includeInactive)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, cities)
+	}
+}
+
+func AddCity(storeInst store.CityManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, ok := bindCityName(c)
+		if !ok {
+			return
+		}
+
+		city, err := storeInst.AddCity(c.Request.Context(), name)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, city)
+	}
+}
+
+// RenameCity renames a city; the old name stays accepted as an alias.
+func RenameCity(storeInst store.CityManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cityID := c.Param("cityId")
+
+		if uuid.Validate(cityID) != nil {
+			apierror.Respond(c, errInvalidCityID)
+			return
+		}
+
+		name, ok := bindCityName(c)
+		if !ok {
+			return
+		}
+
+		city, err := storeInst.RenameCity(c.Request.Context(), cityID, name)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, city)
+	}
+}
+
+// SetCityActive activates or deactivates a city. PVZs already in a
+// deactivated city keep working.
+func SetCityActive(storeInst store.CityManager, active bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cityID := c.Param("cityId")
+
+		if uuid.Validate(cityID) != nil {
+			apierror.Respond(c, errInvalidCityID)
+			return
+		}
+
+		city, err := storeInst.SetCityActive(c.Request.Context(), cityID, active)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, city)
+	}
+}
+
+func bindCityName(c *gin.Context) (model.City, bool) {
+	var req CityInput
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.RespondBinding(c, err)
+		return "", false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		apierror.Respond(c, errInvalidCityName)
+		return "", false
+	}
+
+	return model.City(name), true
+}
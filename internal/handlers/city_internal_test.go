@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCityID = "3d2c1b0a-9e8f-4a7b-8c6d-5e4f3a2b1c0d"
+
+type mockCityStore struct {
+	listFunc   func(ctx context.Context, includeInactive bool) ([]model.CityEntry, error)
+	addFunc    func(ctx context.Context, name model.City) (*model.CityEntry, error)
+	renameFunc func(ctx context.Context, id string, name model.City) (*model.CityEntry, error)
+	activeFunc func(ctx context.Context, id string, active bool) (*model.CityEntry, error)
+}
+
+func (m *mockCityStore) ListCities(ctx context.Context, includeInactive bool) ([]model.CityEntry, error) {
+	return m.listFunc(ctx, includeInactive)
+}
+
+func (m *mockCityStore) AddCity(ctx context.Context, name model.City) (*model.CityEntry, error) {
+	return m.addFunc(ctx, name)
+}
+
+func (m *mockCityStore) RenameCity(ctx context.Context, id string, name model.City) (*model.CityEntry, error) {
+	return m.renameFunc(ctx, id, name)
+}
+
+func (m *mockCityStore) SetCityActive(ctx context.Context, id string, active bool) (*model.CityEntry, error) {
+	return m.activeFunc(ctx, id, active)
+}
+
+func setupCityRouter(mock *mockCityStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/cities", handlers.ListCities(mock))
+	r.POST("/cities", handlers.AddCity(mock))
+	r.POST("/cities/:cityId/rename", handlers.RenameCity(mock))
+	r.POST("/cities/:cityId/deactivate", handlers.SetCityActive(mock, false))
+	return r
+}
+
+func TestListCities(t *testing.T) {
+	var got []bool
+	mock := &mockCityStore{
+		listFunc: func(ctx context.Context, includeInactive bool) ([]model.CityEntry, error) {
+			got = append(got, includeInactive)
+			return []model.CityEntry{{ID: testCityID, Name: model.Kazan, Aliases: []model.City{}, Active: true}}, nil
+		},
+	}
+	r := setupCityRouter(mock)
+
+	w := serve(r, "GET", "/cities", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Казань"`)
+
+	serve(r, "GET", "/cities?includeInactive=true", nil)
+	assert.Equal(t, []bool{false, true}, got)
+
+	w = serve(r, "GET", "/cities?includeInactive=maybe", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_include_inactive"`)
+}
+
+func TestAddCity(t *testing.T) {
+	var got model.City
+	mock := &mockCityStore{
+		addFunc: func(ctx context.Context, name model.City) (*model.CityEntry, error) {
+			got = name
+			return &model.CityEntry{ID: testCityID, Name: name, Aliases: []model.City{}, Active: true}, nil
+		},
+	}
+
+	w := serve(setupCityRouter(mock), "POST", "/cities", map[string]string{"name": "  Тверь "})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, model.City("Тверь"), got, "name is trimmed")
+}
+
+func TestAddCity_Invalid(t *testing.T) {
+	mock := &mockCityStore{
+		addFunc: func(ctx context.Context, name model.City) (*model.CityEntry, error) {
+			return nil, store.ErrCityAlreadyExists
+		},
+	}
+	r := setupCityRouter(mock)
+
+	tests := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"missing name", map[string]string{}, http.StatusBadRequest, "invalid_request"},
+		{"blank name", map[string]string{"name": "   "}, http.StatusBadRequest, "invalid_city_name"},
+		{"taken name", map[string]string{"name": "Москва"}, http.StatusConflict, "city_already_exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, "POST", "/cities", tt.body)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestRenameCity(t *testing.T) {
+	mock := &mockCityStore{
+		renameFunc: func(ctx context.Context, id string, name model.City) (*model.CityEntry, error) {
+			if id != testCityID {
+				return nil, store.ErrCityNotFound
+			}
+			return &model.CityEntry{ID: id, Name: name, Aliases: []model.City{model.SPB}, Active: true}, nil
+		},
+	}
+	r := setupCityRouter(mock)
+
+	w := serve(r, "POST", "/cities/"+testCityID+"/rename", map[string]string{"name": "Петербург"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"aliases":["Санкт-Петербург"]`)
+
+	w = serve(r, "POST", "/cities/"+testPVZID+"/rename", map[string]string{"name": "Петербург"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"city_not_found"`)
+
+	w = serve(r, "POST", "/cities/not-a-uuid/rename", map[string]string{"name": "Петербург"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_city_id"`)
+}
+
+func TestDeactivateCity(t *testing.T) {
+	var got *bool
+	mock := &mockCityStore{
+		activeFunc: func(ctx context.Context, id string, active bool) (*model.CityEntry, error) {
+			got = &active
+			return &model.CityEntry{ID: id, Name: model.Kazan, Aliases: []model.City{}, Active: active}, nil
+		},
+	}
+
+	w := serve(setupCityRouter(mock), "POST", "/cities/"+testCityID+"/deactivate", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, got) {
+		assert.False(t, *got)
+	}
+	assert.Contains(t, w.Body.String(), `"active":false`)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/store"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultEventPollInterval is how often a stream checks for new events.
+	DefaultEventPollInterval = time.Second
+
+	// eventBatchSize caps the events read per poll, a stream that falls
+	// behind catches up over several polls.
+	eventBatchSize = 100
+
+	// heartbeatInterval keeps idle streams from being closed by proxies.
+	heartbeatInterval = 15 * time.Second
+
+	// retryMillis tells clients how long to wait before reconnecting.
+	retryMillis = 3000
+)
+
+var errInvalidLastEventID = apierror.New(http.StatusBadRequest, "invalid_last_event_id", "invalid Last-Event-ID")
+
+// StreamPVZEvents streams the events of a PVZ as Server-Sent Events. A new
+// stream starts with events written after it was opened. A client resuming
+// with Last-Event-ID gets every event written after that ID first, so no
+// event is lost across reconnects. Streams end when shutdown is closed, so
+// they do not hold up a graceful shutdown; clients reconnect and resume.
+func StreamPVZEvents(storeInst store.PVZEventReader, pollInterval time.Duration, shutdown <-chan struct{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pvzID := c.Param("pvzId")
+
+		if uuid.Validate(pvzID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		var resumeFrom *int64
+
+		if header := c.GetHeader("Last-Event-ID"); header != "" {
+			seq, err := strconv.ParseInt(header, 10, 64)
+			if err != nil || seq < 0 {
+				apierror.Respond(c, errInvalidLastEventID)
+				return
+			}
+			resumeFrom = &seq
+		}
+
+		ctx := c.Request.Context()
+
+		// Looked up even on resume, so a missing PVZ is reported before the
+		// stream starts.
+		lastSeq, err := storeInst.LastPVZEventSeq(ctx, pvzID)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		if resumeFrom != nil {
+			lastSeq = *resumeFrom
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", retryMillis)
+		c.Writer.Flush()
+
+		poll := time.NewTicker(pollInterval)
+		defer poll.Stop()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			events, err := storeInst.FetchPVZEvents(ctx, pvzID, lastSeq, eventBatchSize)
+			if err != nil {
+				// The status is already sent, the client reconnects with
+				// the last ID it received.
+				return
+			}
+
+			for _, ev := range events {
+				data, err := json.Marshal(ev)
+				if err != nil {
+					return
+				}
+
+				fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+				lastSeq = ev.Seq
+			}
+
+			if len(events) > 0 {
+				c.Writer.Flush()
+			}
+
+			if len(events) == eventBatchSize {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-shutdown:
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(c.Writer, ": heartbeat\n\n")
+				c.Writer.Flush()
+			case <-poll.C:
+			}
+		}
+	}
+}
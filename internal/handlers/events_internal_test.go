@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockEventReader struct {
+	lastSeqFunc func(ctx context.Context, pvzID string) (int64, error)
+	fetchFunc   func(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error)
+}
+
+func (m *mockEventReader) LastPVZEventSeq(ctx context.Context, pvzID string) (int64, error) {
+	return m.lastSeqFunc(ctx, pvzID)
+}
+
+func (m *mockEventReader) FetchPVZEvents(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+	return m.fetchFunc(ctx, pvzID, afterSeq, limit)
+}
+
+// streamEvents serves a stream until the mock cancels it.
+func streamEvents(ctx context.Context, mock *mockEventReader, pvzID, lastEventID string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/pvz/:pvzId/events", handlers.StreamPVZEvents(mock, time.Millisecond, nil))
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/pvz/"+pvzID+"/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStreamPVZEvents_Success(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data, _ := json.Marshal(map[string]string{"id": "p-1"})
+	var afters []int64
+
+	mock := &mockEventReader{
+		lastSeqFunc: func(ctx context.Context, pvzID string) (int64, error) {
+			return 7, nil
+		},
+		fetchFunc: func(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+			afters = append(afters, afterSeq)
+			if len(afters) == 1 {
+				return []model.Event{{ID: "e-8", Seq: 8, Type: model.EventProductAdded, PVZID: pvzID, Data: data}}, nil
+			}
+			cancel()
+			return []model.Event{}, nil
+		},
+	}
+
+	w := streamEvents(ctx, mock, testPVZID, "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, []int64{7, 8}, afters, "stream starts at the latest event and moves past sent ones")
+	assert.Contains(t, w.Body.String(), "retry: ")
+	assert.Contains(t, w.Body.String(), "id: 8\nevent: product.added\ndata: {\"id\":\"e-8\"")
+	assert.Contains(t, w.Body.String(), `"data":{"id":"p-1"}`)
+}
+
+func TestStreamPVZEvents_Resume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var after int64
+
+	mock := &mockEventReader{
+		lastSeqFunc: func(ctx context.Context, pvzID string) (int64, error) {
+			return 42, nil
+		},
+		fetchFunc: func(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+			after = afterSeq
+			cancel()
+			return []model.Event{}, nil
+		},
+	}
+
+	w := streamEvents(ctx, mock, testPVZID, "3")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(3), after)
+}
+
+func TestStreamPVZEvents_Shutdown(t *testing.T) {
+	shutdown := make(chan struct{})
+	mock := &mockEventReader{
+		lastSeqFunc: func(ctx context.Context, pvzID string) (int64, error) {
+			return 0, nil
+		},
+		fetchFunc: func(ctx context.Context, pvzID string, afterSeq int64, limit int) ([]model.Event, error) {
+			return []model.Event{}, nil
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/pvz/:pvzId/events", handlers.StreamPVZEvents(mock, time.Hour, shutdown))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(r, "GET", "/pvz/"+testPVZID+"/events", nil)
+	}()
+
+	close(shutdown)
+
+	select {
+	case w := <-done:
+		assert.Equal(t, http.StatusOK, w.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end on shutdown")
+	}
+}
+
+func TestStreamPVZEvents_Errors(t *testing.T) {
+	mock := &mockEventReader{
+		lastSeqFunc: func(ctx context.Context, pvzID string) (int64, error) {
+			return 0, store.ErrPVZNotFound
+		},
+	}
+
+	tests := []struct {
+		name        string
+		pvzID       string
+		lastEventID string
+		status      int
+		code        string
+	}{
+		{"invalid pvz ID", "not-a-uuid", "", http.StatusBadRequest, "invalid_pvz_id"},
+		{"invalid Last-Event-ID", testPVZID, "abc", http.StatusBadRequest, "invalid_last_event_id"},
+		{"pvz not found", testPVZID, "", http.StatusNotFound, "pvz_not_found"},
+		{"pvz not found on resume", testPVZID, "5", http.StatusNotFound, "pvz_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := streamEvents(context.Background(), mock, tt.pvzID, tt.lastEventID)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
@@ -1,44 +1,88 @@
 package handlers_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"pvz_server/internal/app/apiserver"
+	"pvz_server/internal/app/authz"
 	"pvz_server/internal/app/deps"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/outbox"
 	"pvz_server/internal/app/store"
+	"pvz_server/internal/app/store/memory"
+	"pvz_server/internal/app/webhook"
+	"pvz_server/internal/handlers"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestFullReceptionFlow(t *testing.T) {
+// newTestStore uses Postgres when DATABASE_URL is set and falls back to the
+// in-memory store otherwise, so the flow can run without a database.
+func newTestStore(t *testing.T) store.Repository {
 	dsn := os.Getenv("DATABASE_URL")
+
+	if dsn == "" {
+		return memory.New()
+	}
+
 	db, err := sql.Open("postgres", dsn)
 
 	if err != nil {
 		t.Fatalf("failed to connect to db: %v", err)
 	}
 
+	return store.New(db)
+}
+
+func newTestKeys(t *testing.T) *jwtkeys.Manager {
+	key, err := jwtkeys.NewHMACKey("test", []byte("integration-secret"))
+	if err != nil {
+		t.Fatalf("failed to create JWT key: %v", err)
+	}
+
+	keys, err := jwtkeys.NewManager("test", key)
+	if err != nil {
+		t.Fatalf("failed to create JWT key manager: %v", err)
+	}
+
+	return keys
+}
+
+func TestFullReceptionFlow(t *testing.T) {
 	s := apiserver.NewServerWithDeps(&deps.Dependencies{
-		Store: store.New(db),
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
 	})
 
 	ts := httptest.NewServer(s.GetEngine())
 	defer ts.Close()
 
-	employeeToken := getToken(t, ts.URL, "employee")
 	moderatorToken := getToken(t, ts.URL, "moderator")
 
 	pvzID := createPVZ(t, ts.URL, moderatorToken, "Казань")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
 
 	createReception(t, ts.URL, employeeToken, pvzID)
 
-	for i := 0; i < 50; i++ {
+	items := make([]map[string]string, 50)
+	for i := range items {
 		var productType string
 		switch {
 		case i < 20:
@@ -48,10 +92,767 @@ func TestFullReceptionFlow(t *testing.T) {
 		default:
 			productType = "обувь"
 		}
-		addProduct(t, ts.URL, employeeToken, pvzID, productType)
+		items[i] = map[string]string{"type": productType}
+	}
+
+	// The pallet is scanned in one request.
+	resp := postJSON(t, ts.URL+"/products/batch", employeeToken, map[string]any{
+		"pvzId": pvzID,
+		"items": items,
+	})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var products []model.Product
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		t.Fatalf("failed to decode products: %v", err)
+	}
+
+	assert.Len(t, products, 50)
+
+	// A mis-scanned item is removed without undoing the scans after it.
+	status, _ := deleteProduct(t, ts.URL, employeeToken, products[10].ID, "wrong item scanned")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, body := deleteProduct(t, ts.URL, employeeToken, products[10].ID, "wrong item scanned")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, body, `"code":"product_not_found"`)
+
+	addProduct(t, ts.URL, employeeToken, pvzID, "обувь")
+	closeReception(t, ts.URL, employeeToken, pvzID)
+
+	// The PVZ has no open reception now.
+	status, body = deleteProduct(t, ts.URL, employeeToken, products[11].ID, "too late")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, body, `"code":"product_reception_closed"`)
+
+	// Nor once a newer reception is open.
+	createReception(t, ts.URL, employeeToken, pvzID)
+
+	status, body = deleteProduct(t, ts.URL, employeeToken, products[11].ID, "too late")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, body, `"code":"product_reception_closed"`)
+}
+
+func TestConcurrentCreateReception(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+
+	const workers = 20
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	codes := make([]int, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = postReception(t, ts.URL, employeeToken, pvzID)
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		}
 	}
 
+	assert.Equal(t, 1, created, "exactly one reception must be opened, got statuses %v", codes)
+}
+
+func TestEmployeeAssignments(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+
+	ownPVZ := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	otherPVZ := createPVZ(t, ts.URL, moderatorToken, "Казань")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, ownPVZ)
+
+	assert.Equal(t, http.StatusForbidden, postReception(t, ts.URL, employeeToken, otherPVZ))
+	assert.Equal(t, http.StatusNotFound, postReception(t, ts.URL, employeeToken, uuid.NewString()))
+	assert.Equal(t, http.StatusCreated, postReception(t, ts.URL, employeeToken, ownPVZ))
+
+	// Tokens issued by dummyLogin belong to no user and so to no PVZ.
+	assert.Equal(t, http.StatusForbidden, postReception(t, ts.URL, getToken(t, ts.URL, "employee"), otherPVZ))
+}
+
+func TestRolePermissions(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	auditorToken := getToken(t, ts.URL, "auditor")
+
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+
+	assert.Equal(t, http.StatusOK, listPVZ(t, ts.URL, auditorToken))
+	assert.Equal(t, http.StatusForbidden, postReception(t, ts.URL, moderatorToken, pvzID))
+
+	resp := postJSON(t, ts.URL+"/pvz", auditorToken, map[string]string{"city": "Москва"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestAuditLog(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+
+	createReception(t, ts.URL, employeeToken, pvzID)
+	addProduct(t, ts.URL, employeeToken, pvzID, "электроника")
 	closeReception(t, ts.URL, employeeToken, pvzID)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/audit?pvzId="+pvzID, nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to fetch audit log: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var events []model.AuditEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode audit log: %v", err)
+	}
+
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, model.AuditReceptionClosed, events[0].Action)
+		assert.Equal(t, string(model.Employee), events[0].ActorRole)
+		assert.NotEmpty(t, events[0].RequestID)
+		assert.Equal(t, model.AuditPVZCreated, events[3].Action)
+		assert.Equal(t, string(model.Moderator), events[3].ActorRole)
+	}
+
+	req, _ = http.NewRequest("GET", ts.URL+"/audit", nil)
+	req.Header.Set("Authorization", "Bearer "+employeeToken)
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to fetch audit log: %v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestPVZEventStream(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+
+	resp, err := http.Get(ts.URL + "/pvz/" + pvzID + "/events")
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/pvz/"+pvzID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	createReception(t, ts.URL, employeeToken, pvzID)
+	addProduct(t, ts.URL, employeeToken, pvzID, "обувь")
+
+	var (
+		ids   []string
+		types []string
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for len(types) < 2 && scanner.Scan() {
+		line := scanner.Text()
+
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+
+		if eventType, ok := strings.CutPrefix(line, "event: "); ok {
+			types = append(types, eventType)
+		}
+	}
+
+	assert.Equal(t, []string{"reception.opened", "product.added"}, types)
+
+	if len(ids) < 2 {
+		t.Fatalf("stream ended early: %v", scanner.Err())
+	}
+
+	// Resuming after the first event replays the second one.
+	req, _ = http.NewRequestWithContext(ctx, "GET", ts.URL+"/pvz/"+pvzID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+moderatorToken)
+	req.Header.Set("Last-Event-ID", ids[0])
+
+	resumed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to resume event stream: %v", err)
+	}
+	defer resumed.Body.Close()
+
+	scanner = bufio.NewScanner(resumed.Body)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+			assert.Equal(t, ids[1], id)
+			break
+		}
+	}
+}
+
+func TestIdempotentRetries(t *testing.T) {
+	repo := newTestStore(t)
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   repo,
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+
+	// A retried reception gets the reception it opened, not a conflict.
+	reception := map[string]string{"pvzId": pvzID}
+	key := uuid.NewString()
+	_, first := postIdempotent(t, ts.URL+"/receptions", employeeToken, key, reception)
+	status, retried := postIdempotent(t, ts.URL+"/receptions", employeeToken, key, reception)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, first, retried)
+
+	product := map[string]string{"pvzId": pvzID, "type": "обувь"}
+	key = uuid.NewString()
+	_, added := postIdempotent(t, ts.URL+"/products", employeeToken, key, product)
+	status, replayed := postIdempotent(t, ts.URL+"/products", employeeToken, key, product)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, added, replayed)
+
+	status, body := postIdempotent(t, ts.URL+"/products", employeeToken, key,
+		map[string]string{"pvzId": pvzID, "type": "одежда"})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Contains(t, body, "idempotency_key_mismatch")
+
+	got, err := repo.FetchPVZ(context.Background(), pvzID)
+	if err != nil {
+		t.Fatalf("failed to fetch pvz: %v", err)
+	}
+
+	if assert.Len(t, got.Receptions, 1) {
+		assert.Contains(t, first, got.Receptions[0].Reception.ID)
+		assert.Len(t, got.Receptions[0].Products, 1)
+	}
+}
+
+// postIdempotent posts body with an Idempotency-Key and returns the status
+// and the response body.
+func postIdempotent(t *testing.T, url, token, key string, body any) (int, string) {
+	data, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request to %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(respBody)
+}
+
+func TestCreatePrivilegedUser(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	credentials := map[string]string{
+		"email":    uuid.NewString() + "@example.com",
+		"password": "password123",
+		"role":     "moderator",
+	}
+
+	resp := postJSON(t, ts.URL+"/register", "", credentials)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/users", "", credentials)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/users", getToken(t, ts.URL, "moderator"), credentials)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/users", getToken(t, ts.URL, "admin"), credentials)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var user model.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		t.Fatalf("failed to decode user: %v", err)
+	}
+
+	assert.Equal(t, model.Moderator, user.Role)
+}
+
+func TestAdminReceptionMutations(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	adminToken := getToken(t, ts.URL, "admin")
+	pvzID := createPVZ(t, ts.URL, adminToken, "Москва")
+
+	// Admins cannot be assigned to a PVZ, so the default policy does not
+	// grant them reception and product mutations at all.
+	tests := []struct {
+		path string
+		body any
+	}{
+		{"/receptions", map[string]string{"pvzId": pvzID}},
+		{"/products", map[string]string{"pvzId": pvzID, "type": "обувь"}},
+		{"/products/batch", map[string]any{"pvzId": pvzID, "items": []map[string]string{{"type": "обувь"}}}},
+		{"/pvz/" + pvzID + "/delete_last_product", nil},
+		{"/pvz/" + pvzID + "/close_last_reception", nil},
+	}
+
+	for _, tt := range tests {
+		resp := postJSON(t, ts.URL+tt.path, adminToken, tt.body)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, tt.path)
+		assert.Contains(t, string(body), `"code":"access_denied"`, tt.path)
+	}
+
+	status, body := deleteProduct(t, ts.URL, adminToken, uuid.NewString(), "cleanup")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, `"code":"access_denied"`)
+}
+
+func TestCityCatalogue(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	name := "Тверь-" + uuid.NewString()
+
+	resp := postJSON(t, ts.URL+"/cities", getToken(t, ts.URL, "employee"), map[string]string{"name": name})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/cities", moderatorToken, map[string]string{"name": name})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var city model.CityEntry
+	if err := json.NewDecoder(resp.Body).Decode(&city); err != nil {
+		t.Fatalf("failed to decode city: %v", err)
+	}
+
+	// A new city is usable at once, without a redeploy.
+	createPVZ(t, ts.URL, moderatorToken, name)
+
+	resp = postJSON(t, ts.URL+"/cities/"+city.ID+"/deactivate", moderatorToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/pvz", moderatorToken, map[string]string{"city": name})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestProductTypeCatalogue(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	code := "косметика-" + uuid.NewString()
+	body := map[string]any{"code": code, "names": map[string]string{"ru": "Косметика"}, "fragile": true}
+
+	resp := postJSON(t, ts.URL+"/product-types", getToken(t, ts.URL, "employee"), body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/product-types", moderatorToken, body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// A new type is accepted at once, without a redeploy.
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+	createReception(t, ts.URL, employeeToken, pvzID)
+	addProduct(t, ts.URL, employeeToken, pvzID, code)
+
+	resp = postJSON(t, ts.URL+"/product-types/"+url.PathEscape(code)+"/deactivate", moderatorToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/products", employeeToken, map[string]string{"pvzId": pvzID, "type": code})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestProductBarcodeLookup(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   newTestStore(t),
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Казань")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+	createReception(t, ts.URL, employeeToken, pvzID)
+
+	barcode := "4600000-" + uuid.NewString()
+	product := map[string]string{"pvzId": pvzID, "type": "обувь", "barcode": barcode, "orderId": "ORD-42"}
+
+	resp := postJSON(t, ts.URL+"/products", employeeToken, product)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = postJSON(t, ts.URL+"/products", employeeToken, product)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var found []model.Product
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/products?barcode="+url.QueryEscape(barcode), employeeToken, &found))
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "ORD-42", found[0].OrderID)
+	}
+
+	// The aggregate carries the same identifiers.
+	var pvzs []model.PVZWithReceptions
+	startDate := url.QueryEscape(time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/pvz?limit=30&startDate="+startDate, moderatorToken, &pvzs))
+
+	for _, p := range pvzs {
+		if p.PVZ.ID == pvzID && assert.Len(t, p.Receptions, 1) {
+			assert.Equal(t, found, p.Receptions[0].Products)
+		}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	repo := newTestStore(t)
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:   repo,
+		Keys:    newTestKeys(t),
+		Policy:  authz.DefaultPolicy(),
+		DevMode: true,
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	var (
+		mu       sync.Mutex
+		received []model.Event
+	)
+
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if !webhook.Verify("partner-secret-123", r.Header.Get(webhook.TimestampHeader), r.Header.Get(webhook.SignatureHeader), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var ev model.Event
+		_ = json.Unmarshal(body, &ev)
+
+		mu.Lock()
+		received = append(received, ev)
+		mu.Unlock()
+	}))
+	defer partner.Close()
+
+	moderatorToken := getToken(t, ts.URL, "moderator")
+
+	resp := postJSON(t, ts.URL+"/webhooks", moderatorToken, map[string]any{
+		"url":        partner.URL,
+		"secret":     "partner-secret-123",
+		"eventTypes": []string{"reception.closed"},
+	})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	pvzID := createPVZ(t, ts.URL, moderatorToken, "Москва")
+	employeeToken := newEmployee(t, ts.URL, moderatorToken, pvzID)
+
+	createReception(t, ts.URL, employeeToken, pvzID)
+	addProduct(t, ts.URL, employeeToken, pvzID, "обувь")
+	closeReception(t, ts.URL, employeeToken, pvzID)
+
+	ctx := context.Background()
+
+	_, err := outbox.NewRelay(repo, webhook.NewDispatcher(repo)).ProcessBatch(ctx)
+	assert.NoError(t, err)
+	_, err = webhook.NewWorker(repo, nil).ProcessBatch(ctx)
+	assert.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, model.EventReceptionClosed, received[0].Type)
+		assert.Equal(t, pvzID, received[0].PVZID)
+	}
+}
+
+// newEmployee registers an employee, assigns them to pvzIDs and returns
+// their access token.
+func newEmployee(t *testing.T, baseURL, moderatorToken string, pvzIDs ...string) string {
+	credentials := map[string]string{
+		"email":    uuid.NewString() + "@example.com",
+		"password": "password123",
+		"role":     "employee",
+	}
+
+	resp := postJSON(t, baseURL+"/register", "", credentials)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var user map[string]string
+	json.NewDecoder(resp.Body).Decode(&user)
+
+	for _, pvzID := range pvzIDs {
+		resp := postJSON(t, baseURL+"/users/"+user["id"]+"/pvz", moderatorToken, map[string]string{"pvzId": pvzID})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	return requestTokens(t, baseURL+"/login", "", credentials).Token
+}
+
+func TestRefreshTokenFlow(t *testing.T) {
+	s := apiserver.NewServerWithDeps(&deps.Dependencies{
+		Store:  newTestStore(t),
+		Keys:   newTestKeys(t),
+		Policy: authz.DefaultPolicy(),
+	})
+
+	ts := httptest.NewServer(s.GetEngine())
+	defer ts.Close()
+
+	email := uuid.NewString() + "@example.com"
+	credentials := map[string]string{"email": email, "password": "password123", "role": "employee"}
+
+	resp := postJSON(t, ts.URL+"/register", "", credentials)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	login := requestTokens(t, ts.URL+"/login", "", credentials)
+	rotated := requestTokens(t, ts.URL+"/auth/refresh", "", map[string]string{"refreshToken": login.RefreshToken})
+
+	assert.Equal(t, http.StatusOK, listPVZ(t, ts.URL, rotated.Token))
+
+	// Replaying the spent refresh token revokes everything issued since login.
+	resp = postJSON(t, ts.URL+"/auth/refresh", "", map[string]string{"refreshToken": login.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, listPVZ(t, ts.URL, rotated.Token))
+
+	resp = postJSON(t, ts.URL+"/auth/refresh", "", map[string]string{"refreshToken": rotated.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	// A fresh login is unaffected and can log out.
+	session := requestTokens(t, ts.URL+"/login", "", credentials)
+
+	// Nobody else can log it out.
+	intruder := map[string]string{"email": uuid.NewString() + "@example.com", "password": "password123"}
+	resp = postJSON(t, ts.URL+"/register", "", intruder)
+	resp.Body.Close()
+	intruderSession := requestTokens(t, ts.URL+"/login", "", intruder)
+
+	resp = postJSON(t, ts.URL+"/auth/logout", intruderSession.Token, map[string]string{"refreshToken": session.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, listPVZ(t, ts.URL, session.Token))
+
+	resp = postJSON(t, ts.URL+"/auth/logout", session.Token, map[string]string{"refreshToken": session.RefreshToken})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, listPVZ(t, ts.URL, session.Token))
+}
+
+func postJSON(t *testing.T, url, token string, body any) *http.Response {
+	data, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("request to %s failed: %v", url, err)
+	}
+
+	return resp
+}
+
+func requestTokens(t *testing.T, url, token string, body any) handlers.TokenPair {
+	resp := postJSON(t, url, token, body)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var pair handlers.TokenPair
+	json.NewDecoder(resp.Body).Decode(&pair)
+
+	return pair
+}
+
+func listPVZ(t *testing.T, baseURL, token string) int {
+	req, _ := http.NewRequest("GET", baseURL+"/pvz", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("failed to list PVZ: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func getJSON(t *testing.T, url, token string, out any) int {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("failed to decode %s: %v", url, err)
+	}
+
+	return resp.StatusCode
 }
 
 func getToken(t *testing.T, baseURL, role string) string {
@@ -105,6 +906,10 @@ func createPVZ(t *testing.T, baseURL, token, city string) string {
 }
 
 func createReception(t *testing.T, baseURL, token, pvzID string) {
+	assert.Equal(t, http.StatusCreated, postReception(t, baseURL, token, pvzID))
+}
+
+func postReception(t *testing.T, baseURL, token, pvzID string) int {
 	body := map[string]string{"pvzId": pvzID}
 	data, _ := json.Marshal(body)
 
@@ -115,12 +920,13 @@ func createReception(t *testing.T, baseURL, token, pvzID string) {
 	resp, err := http.DefaultClient.Do(req)
 
 	if err != nil {
-		t.Fatalf("failed to create reception: %v", err)
+		t.Errorf("failed to create reception: %v", err)
+		return 0
 	}
 
 	defer resp.Body.Close()
 
-	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	return resp.StatusCode
 }
 
 func addProduct(t *testing.T, baseURL, token, pvzID, productType string) {
@@ -142,6 +948,27 @@ func addProduct(t *testing.T, baseURL, token, pvzID, productType string) {
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 }
 
+// deleteProduct returns the status and body of the response.
+func deleteProduct(t *testing.T, baseURL, token, productID, reason string) (int, string) {
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+
+	req, _ := http.NewRequest("DELETE", baseURL+"/products/"+productID, bytes.NewBuffer(data))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("failed to delete product: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body)
+}
+
 func closeReception(t *testing.T, baseURL, token, pvzID string) {
 	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/pvz/%s/close_last_reception", baseURL, pvzID), nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz_server/internal/app/jwtkeys"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public keys that verify access tokens, so other
+// services can check them without sharing a secret.
+func JWKS(keys *jwtkeys.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keys.JWKS())
+	}
+}
@@ -0,0 +1,36 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/jwtkeys"
+	"pvz_server/internal/handlers"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS_HidesHMACSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	router := gin.Default()
+	router.GET("/.well-known/jwks.json", handlers.JWKS(testKeys))
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var set jwtkeys.JWKSet
+	err := json.Unmarshal(resp.Body.Bytes(), &set)
+
+	assert.NoError(t, err)
+	assert.Empty(t, set.Keys)
+	assert.NotContains(t, resp.Body.String(), "test-secret")
+}
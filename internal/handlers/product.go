@@ -2,80 +2,269 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"pvz_server/internal/app/apierror"
 	"pvz_server/internal/app/model"
 	"pvz_server/internal/app/store"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errMissingBarcode = apierror.New(http.StatusBadRequest, "missing_barcode", "barcode is required")
+	errInvalidReason  = apierror.New(http.StatusBadRequest, "invalid_reason", "reason must not be blank")
 )
 
 type ProductInput struct {
-	Type  model.ProductType `json:"type" binding:"required"`
-	PVZID string            `json:"pvzId" binding:"required"`
+	Type    model.ProductType `json:"type" binding:"required"`
+	PVZID   string            `json:"pvzId" binding:"required"`
+	Barcode string            `json:"barcode" binding:"omitempty,max=64,printascii"`
+	OrderID string            `json:"orderId" binding:"omitempty,max=64,printascii"`
 }
 
-func AddProduct(storeInst store.ProductAdder) gin.HandlerFunc {
+func AddProduct(storeInst store.ProductAdder, assignments store.AssignmentChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
+		var req ProductInput
 
-		if !ok || role != "employee" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
+		if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
 			return
 		}
 
-		var req ProductInput
+		if uuid.Validate(req.PVZID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		if !ensureAssigned(c, assignments, req.PVZID) {
+			return
+		}
+
+		product, err := storeInst.AddProduct(c.Request.Context(), req.PVZID, store.ProductDraft{
+			Type:    req.Type,
+			Barcode: strings.TrimSpace(req.Barcode),
+			OrderID: strings.TrimSpace(req.OrderID),
+		})
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, product)
+	}
+}
+
+type BatchProductItem struct {
+	Type     model.ProductType `json:"type" binding:"required"`
+	ClientID string            `json:"clientId" binding:"omitempty,uuid"`
+	Barcode  string            `json:"barcode" binding:"omitempty,max=64,printascii"`
+	OrderID  string            `json:"orderId" binding:"omitempty,max=64,printascii"`
+}
+
+type BatchProductInput struct {
+	PVZID string             `json:"pvzId" binding:"required"`
+	Items []BatchProductItem `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+// AddProducts adds a batch of products to the active reception in one
+// transaction. Created products come back in the order of items; a client
+// ID becomes the ID of its product. Invalid items are listed in details and
+// nothing is added.
+func AddProducts(storeInst store.ProductBatchAdder, assignments store.AssignmentChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BatchProductInput
 
 		if err := c.ShouldBindBodyWithJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request"})
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		if uuid.Validate(req.PVZID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
+
+		for i := range req.Items {
+			req.Items[i].Barcode = strings.TrimSpace(req.Items[i].Barcode)
+			req.Items[i].OrderID = strings.TrimSpace(req.Items[i].OrderID)
+		}
+
+		if err := validateBatchItems(req.Items); err != nil {
+			apierror.Respond(c, err)
 			return
 		}
 
-		product, err := storeInst.AddProduct(c.Request.Context(), req.PVZID, req.Type)
+		if !ensureAssigned(c, assignments, req.PVZID) {
+			return
+		}
+
+		drafts := make([]store.ProductDraft, len(req.Items))
+		for i, item := range req.Items {
+			drafts[i] = store.ProductDraft{
+				ID:      item.ClientID,
+				Type:    item.Type,
+				Barcode: item.Barcode,
+				OrderID: item.OrderID,
+			}
+		}
 
-		switch {
-		case errors.Is(err, store.ErrProductTypeNotAllowed):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unsupported product type"})
-		case errors.Is(err, store.ErrNoActiveReception):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "no active reception"})
-		case errors.Is(err, store.ErrDatabase):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to add product"})
-		case err != nil:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unexpected error"})
-		default:
-			c.JSON(http.StatusCreated, product)
+		products, err := storeInst.AddProducts(c.Request.Context(), req.PVZID, drafts)
+		if err != nil {
+			apierror.Respond(c, productTypeDetails(err))
+			return
 		}
+
+		c.JSON(http.StatusCreated, products)
 	}
 }
 
-func DeleteLastProduct(storeInst store.ProductDeleter) gin.HandlerFunc {
+// validateBatchItems reports every client ID and barcode repeated within
+// the batch.
+func validateBatchItems(items []BatchProductItem) error {
+	var details []apierror.FieldError
+
+	seenIDs := make(map[string]bool, len(items))
+	seenBarcodes := make(map[string]bool, len(items))
+
+	repeated := func(seen map[string]bool, value, field string, i int) {
+		if value == "" {
+			return
+		}
+
+		if seen[value] {
+			details = append(details, apierror.FieldError{
+				Field:   fmt.Sprintf("items[%d].%s", i, field),
+				Message: "is repeated in the batch",
+			})
+		}
+
+		seen[value] = true
+	}
+
+	for i, item := range items {
+		repeated(seenIDs, item.ClientID, "clientId", i)
+		repeated(seenBarcodes, item.Barcode, "barcode", i)
+	}
+
+	if len(details) > 0 {
+		apiErr := *apierror.ErrInvalidRequest
+		apiErr.Details = details
+		return &apiErr
+	}
+
+	return nil
+}
+
+// productTypeDetails points at every item whose type the catalogue rejected.
+func productTypeDetails(err error) error {
+	var typeErr *store.ProductTypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	apiErr := *apierror.From(store.ErrProductTypeNotAllowed)
+	apiErr.Details = make([]apierror.FieldError, len(typeErr.Indexes))
+
+	for i, idx := range typeErr.Indexes {
+		apiErr.Details[i] = apierror.FieldError{
+			Field:   fmt.Sprintf("items[%d].type", idx),
+			Message: "unsupported product type",
+		}
+	}
+
+	return &apiErr
+}
+
+// FindProducts looks products up by barcode, newest first. A barcode is
+// unique only within a reception, so several products may match.
+func FindProducts(storeInst store.ProductFinder) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
+		barcode := strings.TrimSpace(c.Query("barcode"))
+		if barcode == "" {
+			apierror.Respond(c, errMissingBarcode)
+			return
+		}
 
-		if !ok || role != "employee" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
+		products, err := storeInst.FindProductsByBarcode(c.Request.Context(), barcode)
+		if err != nil {
+			apierror.Respond(c, err)
 			return
 		}
 
+		c.JSON(http.StatusOK, products)
+	}
+}
+
+func DeleteLastProduct(storeInst store.ProductDeleter, assignments store.AssignmentChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		pvzID := c.Param("pvzId")
 
-		if pvzID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid pvz ID"})
+		if uuid.Validate(pvzID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
 			return
 		}
 
-		err := storeInst.DeleteLastProduct(c.Request.Context(), pvzID)
+		if !ensureAssigned(c, assignments, pvzID) {
+			return
+		}
 
-		switch {
-		case errors.Is(err, store.ErrNoActiveReception):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "no active reception"})
-		case errors.Is(err, store.ErrNoProductsToDelete):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "no products to delete"})
-		case errors.Is(err, store.ErrDatabase):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to delete product"})
-		case err != nil:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unexpected error"})
-		default:
-			c.JSON(http.StatusOK, gin.H{"message": "product deleted"})
+		if err := storeInst.DeleteLastProduct(c.Request.Context(), pvzID); err != nil {
+			apierror.Respond(c, err)
+			return
 		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "product deleted"})
+	}
+}
+
+type DeleteProductInput struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// DeleteProduct deletes any product of the open reception it belongs to;
+// the reason is kept in the audit log. delete_last_product stays for
+// clients that only undo the last scan.
+func DeleteProduct(storeInst store.ProductRemover, assignments store.AssignmentChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID := c.Param("productId")
+
+		if uuid.Validate(productID) != nil {
+			apierror.Respond(c, store.ErrInvalidProductID)
+			return
+		}
+
+		var req DeleteProductInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		reason := strings.TrimSpace(req.Reason)
+		if reason == "" {
+			apierror.Respond(c, errInvalidReason)
+			return
+		}
+
+		pvzID, err := storeInst.ProductPVZ(c.Request.Context(), productID)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		if !ensureAssigned(c, assignments, pvzID) {
+			return
+		}
+
+		product, err := storeInst.DeleteProduct(c.Request.Context(), pvzID, productID, reason)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, product)
 	}
 }
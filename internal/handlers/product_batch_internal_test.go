@@ -0,0 +1,202 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientID = "5b0e2c9e-8f51-4f27-9d0b-3c1a2e7f6d44"
+
+type mockBatchStore struct {
+	addFunc func(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error)
+}
+
+func (m *mockBatchStore) AddProducts(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+	return m.addFunc(ctx, pvzID, drafts)
+}
+
+func postBatch(mock *mockBatchStore, body any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/products/batch", handlers.AddProducts(mock, assignedTo(testPVZID)))
+
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/products/batch", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAddProducts_Success(t *testing.T) {
+	var got []store.ProductDraft
+	mock := &mockBatchStore{
+		addFunc: func(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+			got = drafts
+			return []model.Product{
+				{ID: testClientID, Type: model.Shoes, ReceptionID: "r-1"},
+				{ID: "p-2", Type: model.Clothing, ReceptionID: "r-1"},
+			}, nil
+		},
+	}
+
+	w := postBatch(mock, map[string]any{
+		"pvzId": testPVZID,
+		"items": []map[string]string{
+			{"type": "обувь", "clientId": testClientID},
+			{"type": "одежда"},
+		},
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []store.ProductDraft{
+		{ID: testClientID, Type: model.Shoes},
+		{Type: model.Clothing},
+	}, got)
+
+	var products []model.Product
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+	assert.Len(t, products, 2)
+}
+
+func TestAddProducts_ItemErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []map[string]string
+		status  int
+		code    string
+		details []apierror.FieldError
+	}{
+		{
+			name:   "repeated client ID",
+			items:  []map[string]string{{"type": "обувь", "clientId": testClientID}, {"type": "обувь", "clientId": testClientID}},
+			status: http.StatusBadRequest,
+			code:   "invalid_request",
+			details: []apierror.FieldError{
+				{Field: "items[1].clientId", Message: "is repeated in the batch"},
+			},
+		},
+		{
+			name:   "repeated barcode",
+			items:  []map[string]string{{"type": "обувь", "barcode": "4600001"}, {"type": "обувь"}, {"type": "одежда", "barcode": " 4600001 "}},
+			status: http.StatusBadRequest,
+			code:   "invalid_request",
+			details: []apierror.FieldError{
+				{Field: "items[2].barcode", Message: "is repeated in the batch"},
+			},
+		},
+		{
+			name:   "invalid client ID",
+			items:  []map[string]string{{"type": "обувь"}, {"type": "обувь", "clientId": "abc"}},
+			status: http.StatusBadRequest,
+			code:   "invalid_request",
+			details: []apierror.FieldError{
+				{Field: "items[1].clientId", Message: "must be a valid UUID"},
+			},
+		},
+		{
+			name:   "missing type",
+			items:  []map[string]string{{"clientId": testClientID}},
+			status: http.StatusBadRequest,
+			code:   "invalid_request",
+			details: []apierror.FieldError{
+				{Field: "items[0].type", Message: "is required"},
+			},
+		},
+		{
+			name:   "empty batch",
+			items:  []map[string]string{},
+			status: http.StatusBadRequest,
+			code:   "invalid_request",
+			details: []apierror.FieldError{
+				{Field: "items", Message: "must contain at least 1 items"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockBatchStore{
+				addFunc: func(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+					t.Fatal("store must not be called")
+					return nil, nil
+				},
+			}
+
+			w := postBatch(mock, map[string]any{"pvzId": testPVZID, "items": tt.items})
+
+			var resp apierror.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, resp.Code)
+			assert.Equal(t, tt.details, resp.Details)
+		})
+	}
+}
+
+func TestAddProducts_UnsupportedTypes(t *testing.T) {
+	mock := &mockBatchStore{
+		addFunc: func(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+			return nil, &store.ProductTypeError{Indexes: []int{1, 2}}
+		},
+	}
+
+	w := postBatch(mock, map[string]any{
+		"pvzId": testPVZID,
+		"items": []map[string]string{{"type": "обувь"}, {"type": "мебель"}, {"type": "еда"}},
+	})
+
+	var resp apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "product_type_not_allowed", resp.Code)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "items[1].type", Message: "unsupported product type"},
+		{Field: "items[2].type", Message: "unsupported product type"},
+	}, resp.Details)
+}
+
+func TestAddProducts_StoreErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{store.ErrNoActiveReception, http.StatusConflict, "no_active_reception"},
+		{store.ErrProductAlreadyExists, http.StatusConflict, "product_already_exists"},
+		{store.ErrPVZNotFound, http.StatusNotFound, "pvz_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			mock := &mockBatchStore{
+				addFunc: func(ctx context.Context, pvzID string, drafts []store.ProductDraft) ([]model.Product, error) {
+					return nil, tt.err
+				},
+			}
+
+			w := postBatch(mock, map[string]any{
+				"pvzId": testPVZID,
+				"items": []map[string]string{{"type": "обувь"}},
+			})
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"pvz_server/internal/app/model"
 	"pvz_server/internal/app/store"
 	"pvz_server/internal/handlers"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,12 +19,12 @@ import (
 )
 
 type mockProductStore struct {
-	addFunc    func(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error)
+	addFunc    func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error)
 	deleteFunc func(ctx context.Context, pvzID string) error
 }
 
-func (m *mockProductStore) AddProduct(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
-	return m.addFunc(ctx, pvzID, productType)
+func (m *mockProductStore) AddProduct(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+	return m.addFunc(ctx, pvzID, draft)
 }
 
 func (m *mockProductStore) DeleteLastProduct(ctx context.Context, pvzID string) error {
@@ -44,7 +45,7 @@ func setupProductRouterWithRole(role string, store *mockProductStore) *gin.Engin
 		c.Next()
 	})
 
-	r.POST("/products", handlers.AddProduct(store))
+	r.POST("/products", handlers.AddProduct(store, assignedTo(testPVZID)))
 	return r
 }
 
@@ -59,17 +60,17 @@ func setupDeleteProductRouterWithRole(role string, store *mockProductStore) *gin
 		c.Next()
 	})
 
-	r.POST("/pvz/:pvzId/delete_last_product", handlers.DeleteLastProduct(store))
+	r.POST("/pvz/:pvzId/delete_last_product", handlers.DeleteLastProduct(store, assignedTo(testPVZID)))
 	return r
 }
 
 func TestAddProduct_Success(t *testing.T) {
 	mock := &mockProductStore{
-		addFunc: func(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
 			return &model.Product{
 				ID:          "p-123",
 				DateTime:    time.Now(),
-				Type:        productType,
+				Type:        draft.Type,
 				ReceptionID: "r-123",
 			}, nil
 		},
@@ -77,7 +78,7 @@ func TestAddProduct_Success(t *testing.T) {
 
 	router := setupProductRouterWithRole("employee", mock)
 
-	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
+	body := map[string]string{"type": "электроника", "pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
@@ -90,23 +91,6 @@ func TestAddProduct_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), `"type":"электроника"`)
 }
 
-func TestAddProduct_InvalidRole(t *testing.T) {
-	mock := &mockProductStore{}
-	router := setupProductRouterWithRole("moderator", mock)
-
-	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
-	jsonBody, _ := json.Marshal(body)
-
-	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
-}
-
 func TestAddProduct_InvalidBody(t *testing.T) {
 	mock := &mockProductStore{}
 	router := setupProductRouterWithRole("employee", mock)
@@ -123,13 +107,13 @@ func TestAddProduct_InvalidBody(t *testing.T) {
 
 func TestAddProduct_NoActiveReception(t *testing.T) {
 	mock := &mockProductStore{
-		addFunc: func(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
 			return nil, store.ErrNoActiveReception
 		},
 	}
 	router := setupProductRouterWithRole("employee", mock)
 
-	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
+	body := map[string]string{"type": "электроника", "pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
@@ -138,19 +122,19 @@ func TestAddProduct_NoActiveReception(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
 	assert.Contains(t, w.Body.String(), "no active reception")
 }
 
 func TestAddProduct_DatabaseError(t *testing.T) {
 	mock := &mockProductStore{
-		addFunc: func(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
 			return nil, store.ErrDatabase
 		},
 	}
 	router := setupProductRouterWithRole("employee", mock)
 
-	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
+	body := map[string]string{"type": "электроника", "pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
@@ -159,19 +143,19 @@ func TestAddProduct_DatabaseError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "failed to add product")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database is unavailable")
 }
 
 func TestAddProduct_UnexpectedError(t *testing.T) {
 	mock := &mockProductStore{
-		addFunc: func(ctx context.Context, pvzID string, productType model.ProductType) (*model.Product, error) {
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
 			return nil, errors.New("unknown")
 		},
 	}
 	router := setupProductRouterWithRole("employee", mock)
 
-	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
+	body := map[string]string{"type": "электроника", "pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
@@ -180,8 +164,87 @@ func TestAddProduct_UnexpectedError(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "internal server error")
+}
+
+func TestAddProduct_BarcodeAndOrderID(t *testing.T) {
+	var got store.ProductDraft
+	mock := &mockProductStore{
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+			got = draft
+			return &model.Product{ID: "p-123", Type: draft.Type, Barcode: draft.Barcode, OrderID: draft.OrderID}, nil
+		},
+	}
+	router := setupProductRouterWithRole("employee", mock)
+
+	w := serve(router, "POST", "/products", map[string]string{
+		"type":    "обувь",
+		"pvzId":   testPVZID,
+		"barcode": " 4600000000017 ",
+		"orderId": "ORD-42",
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, store.ProductDraft{Type: model.Shoes, Barcode: "4600000000017", OrderID: "ORD-42"}, got)
+	assert.Contains(t, w.Body.String(), `"barcode":"4600000000017","orderId":"ORD-42"`)
+
+	w = serve(router, "POST", "/products", map[string]string{
+		"type":    "обувь",
+		"pvzId":   testPVZID,
+		"barcode": strings.Repeat("4", 65),
+	})
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "unexpected error")
+	assert.Contains(t, w.Body.String(), `"field":"barcode"`)
+}
+
+func TestAddProduct_BarcodeTaken(t *testing.T) {
+	mock := &mockProductStore{
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+			return nil, store.ErrBarcodeAlreadyExists
+		},
+	}
+
+	w := serve(setupProductRouterWithRole("employee", mock), "POST", "/products", map[string]string{
+		"type":    "обувь",
+		"pvzId":   testPVZID,
+		"barcode": "4600000000017",
+	})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"barcode_already_exists"`)
+}
+
+type mockProductFinder struct {
+	findFunc func(ctx context.Context, barcode string) ([]model.Product, error)
+}
+
+func (m *mockProductFinder) FindProductsByBarcode(ctx context.Context, barcode string) ([]model.Product, error) {
+	return m.findFunc(ctx, barcode)
+}
+
+func TestFindProducts(t *testing.T) {
+	var got []string
+	mock := &mockProductFinder{
+		findFunc: func(ctx context.Context, barcode string) ([]model.Product, error) {
+			got = append(got, barcode)
+			return []model.Product{{ID: "p-1", Type: model.Shoes, Barcode: barcode, OrderID: "ORD-42"}}, nil
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/products", handlers.FindProducts(mock))
+
+	w := serve(r, "GET", "/products?barcode=4600000000017", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"orderId":"ORD-42"`)
+	assert.Equal(t, []string{"4600000000017"}, got)
+
+	w = serve(r, "GET", "/products", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"missing_barcode"`)
+	assert.Len(t, got, 1)
 }
 
 func TestDeleteLastProduct_Success(t *testing.T) {
@@ -192,7 +255,7 @@ func TestDeleteLastProduct_Success(t *testing.T) {
 	}
 	router := setupDeleteProductRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/delete_last_product", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/delete_last_product", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -200,18 +263,6 @@ func TestDeleteLastProduct_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "product deleted")
 }
 
-func TestDeleteLastProduct_InvalidRole(t *testing.T) {
-	mock := &mockProductStore{}
-	router := setupDeleteProductRouterWithRole("moderator", mock)
-
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/delete_last_product", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
-}
-
 func TestDeleteLastProduct_NoActiveReception(t *testing.T) {
 	mock := &mockProductStore{
 		deleteFunc: func(ctx context.Context, pvzID string) error {
@@ -220,11 +271,11 @@ func TestDeleteLastProduct_NoActiveReception(t *testing.T) {
 	}
 	router := setupDeleteProductRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/delete_last_product", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/delete_last_product", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
 	assert.Contains(t, w.Body.String(), "no active reception")
 }
 
@@ -236,11 +287,11 @@ func TestDeleteLastProduct_NoProducts(t *testing.T) {
 	}
 	router := setupDeleteProductRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/delete_last_product", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/delete_last_product", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
 	assert.Contains(t, w.Body.String(), "no products to delete")
 }
 
@@ -252,10 +303,151 @@ func TestDeleteLastProduct_UnexpectedError(t *testing.T) {
 	}
 	router := setupDeleteProductRouterWithRole("employee", mock)
 
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/delete_last_product", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "internal server error")
+}
+
+func TestAddProduct_InvalidPVZID(t *testing.T) {
+	mock := &mockProductStore{}
+	router := setupProductRouterWithRole("employee", mock)
+
+	body := map[string]string{"type": "электроника", "pvzId": "pvz1"}
+	jsonBody, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid pvz ID")
+}
+
+func TestAddProduct_PVZNotFound(t *testing.T) {
+	mock := &mockProductStore{
+		addFunc: func(ctx context.Context, pvzID string, draft store.ProductDraft) (*model.Product, error) {
+			return nil, store.ErrPVZNotFound
+		},
+	}
+	router := setupProductRouterWithRole("employee", mock)
+
+	body := map[string]string{"type": "электроника", "pvzId": testPVZID}
+	jsonBody, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "pvz not found")
+}
+
+func TestDeleteLastProduct_InvalidPVZID(t *testing.T) {
+	mock := &mockProductStore{}
+	router := setupDeleteProductRouterWithRole("employee", mock)
+
 	req, _ := http.NewRequest("POST", "/pvz/pvz1/delete_last_product", nil)
 	w := httptest.NewRecorder()
+
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "unexpected error")
+	assert.Contains(t, w.Body.String(), "invalid pvz ID")
+}
+
+func TestDeleteLastProduct_PVZNotFound(t *testing.T) {
+	mock := &mockProductStore{
+		deleteFunc: func(ctx context.Context, pvzID string) error {
+			return store.ErrPVZNotFound
+		},
+	}
+	router := setupDeleteProductRouterWithRole("employee", mock)
+
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/delete_last_product", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "pvz not found")
+}
+
+const (
+	testProductID     = "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f"
+	otherPVZProductID = "0f1e2d3c-4b5a-4968-8776-655443322110"
+	unknownProductID  = "11111111-2222-4333-8444-555555555555"
+	closedProductID   = "66666666-7777-4888-9999-aaaaaaaaaaaa"
+	otherPVZID        = "7e6d5c4b-3a29-4817-9605-f4e3d2c1b0a9"
+)
+
+type mockProductRemover struct {
+	deleted []string
+	reasons []string
+}
+
+func (m *mockProductRemover) ProductPVZ(ctx context.Context, productID string) (string, error) {
+	switch productID {
+	case testProductID, closedProductID:
+		return testPVZID, nil
+	case otherPVZProductID:
+		return otherPVZID, nil
+	default:
+		return "", store.ErrProductNotFound
+	}
+}
+
+func (m *mockProductRemover) DeleteProduct(ctx context.Context, pvzID, productID, reason string) (*model.Product, error) {
+	if productID == closedProductID {
+		return nil, store.ErrProductReceptionClosed
+	}
+
+	m.deleted = append(m.deleted, productID)
+	m.reasons = append(m.reasons, reason)
+	return &model.Product{ID: productID, Type: model.Shoes}, nil
+}
+
+func TestDeleteProduct(t *testing.T) {
+	mock := &mockProductRemover{}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/products/:productId", handlers.DeleteProduct(mock, assignedTo(testPVZID)))
+
+	w := serve(r, "DELETE", "/products/"+testProductID, map[string]string{"reason": "  wrong item scanned "})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"`+testProductID+`"`)
+	assert.Equal(t, []string{"wrong item scanned"}, mock.reasons)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"invalid ID", "/products/abc", map[string]string{"reason": "x"}, http.StatusBadRequest, "invalid_product_id"},
+		{"missing reason", "/products/" + testProductID, map[string]string{}, http.StatusBadRequest, "invalid_request"},
+		{"blank reason", "/products/" + testProductID, map[string]string{"reason": "  "}, http.StatusBadRequest, "invalid_reason"},
+		{"unknown product", "/products/" + unknownProductID, map[string]string{"reason": "x"}, http.StatusNotFound, "product_not_found"},
+		{"not assigned", "/products/" + otherPVZProductID, map[string]string{"reason": "x"}, http.StatusForbidden, "pvz_not_assigned"},
+		{"closed reception", "/products/" + closedProductID, map[string]string{"reason": "x"}, http.StatusConflict, "product_reception_closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, "DELETE", tt.path, tt.body)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+
+	assert.Equal(t, []string{testProductID}, mock.deleted)
 }
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidProductTypeCode  = apierror.New(http.StatusBadRequest, "invalid_product_type_code", "product type code must not be blank")
+	errInvalidProductTypeNames = apierror.New(http.StatusBadRequest, "invalid_product_type_names", "names must map locales like \"ru\" or \"en-US\" to non-blank names")
+)
+
+// localePattern accepts language tags such as "ru", "en" or "en-US".
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+type NewProductTypeInput struct {
+	Code           string            `json:"code" binding:"required,max=50"`
+	Names          map[string]string `json:"names" binding:"required"`
+	Fragile        bool              `json:"fragile"`
+	MaxWeightGrams *int              `json:"maxWeightGrams" binding:"omitempty,min=1"`
+}
+
+type ProductTypeInput struct {
+	Names          map[string]string `json:"names" binding:"required"`
+	Fragile        bool              `json:"fragile"`
+	MaxWeightGrams *int              `json:"maxWeightGrams" binding:"omitempty,min=1"`
+}
+
+// ListProductTypes lists active product types, and inactive ones with
+// includeInactive=true.
+func ListProductTypes(storeInst store.ProductTypeManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		includeInactive, err := strconv.ParseBool(c.DefaultQuery("includeInactive", "false"))
+		if err != nil {
+			apierror.Respond(c, errInvalidIncludeInactive)
+			return
+		}
+
+		types, err := storeInst.ListProductTypes(c.Request.Context(), includeInactive)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, types)
+	}
+}
+
+func AddProductType(storeInst store.ProductTypeManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req NewProductTypeInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		code := strings.TrimSpace(req.Code)
+		if code == "" {
+			apierror.Respond(c, errInvalidProductTypeCode)
+			return
+		}
+
+		names, ok := normalizeNames(req.Names)
+		if !ok {
+			apierror.Respond(c, errInvalidProductTypeNames)
+			return
+		}
+
+		pt, err := storeInst.AddProductType(c.Request.Context(), model.ProductTypeEntry{
+			Code:           model.ProductType(code),
+			Names:          names,
+			Fragile:        req.Fragile,
+			MaxWeightGrams: req.MaxWeightGrams,
+		})
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, pt)
+	}
+}
+
+// UpdateProductType replaces the names and attributes of a product type.
+// The code is fixed, since products refer to it.
+func UpdateProductType(storeInst store.ProductTypeManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ProductTypeInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		names, ok := normalizeNames(req.Names)
+		if !ok {
+			apierror.Respond(c, errInvalidProductTypeNames)
+			return
+		}
+
+		pt, err := storeInst.UpdateProductType(c.Request.Context(), model.ProductTypeEntry{
+			Code:           model.ProductType(c.Param("code")),
+			Names:          names,
+			Fragile:        req.Fragile,
+			MaxWeightGrams: req.MaxWeightGrams,
+		})
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pt)
+	}
+}
+
+// SetProductTypeActive activates or deactivates a product type. Products
+// already accepted with a deactivated type are kept.
+func SetProductTypeActive(storeInst store.ProductTypeManager, active bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pt, err := storeInst.SetProductTypeActive(c.Request.Context(), model.ProductType(c.Param("code")), active)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pt)
+	}
+}
+
+// normalizeNames trims the display names and reports false unless there is
+// at least one and every locale and name is valid.
+func normalizeNames(names map[string]string) (map[string]string, bool) {
+	if len(names) == 0 {
+		return nil, false
+	}
+
+	out := make(map[string]string, len(names))
+
+	for locale, name := range names {
+		name = strings.TrimSpace(name)
+		if !localePattern.MatchString(locale) || name == "" {
+			return nil, false
+		}
+		out[locale] = name
+	}
+
+	return out, true
+}
@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockProductTypeStore struct {
+	listFunc   func(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error)
+	addFunc    func(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error)
+	updateFunc func(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error)
+	activeFunc func(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error)
+}
+
+func (m *mockProductTypeStore) ListProductTypes(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error) {
+	return m.listFunc(ctx, includeInactive)
+}
+
+func (m *mockProductTypeStore) AddProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	return m.addFunc(ctx, pt)
+}
+
+func (m *mockProductTypeStore) UpdateProductType(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+	return m.updateFunc(ctx, pt)
+}
+
+func (m *mockProductTypeStore) SetProductTypeActive(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error) {
+	return m.activeFunc(ctx, code, active)
+}
+
+func setupProductTypeRouter(mock *mockProductTypeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/product-types", handlers.ListProductTypes(mock))
+	r.POST("/product-types", handlers.AddProductType(mock))
+	r.PUT("/product-types/:code", handlers.UpdateProductType(mock))
+	r.POST("/product-types/:code/deactivate", handlers.SetProductTypeActive(mock, false))
+	return r
+}
+
+func TestListProductTypes(t *testing.T) {
+	var got []bool
+	mock := &mockProductTypeStore{
+		listFunc: func(ctx context.Context, includeInactive bool) ([]model.ProductTypeEntry, error) {
+			got = append(got, includeInactive)
+			return model.DefaultProductTypes, nil
+		},
+	}
+	r := setupProductTypeRouter(mock)
+
+	w := serve(r, "GET", "/product-types", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"электроника"`)
+	assert.Contains(t, w.Body.String(), `"fragile":true`)
+
+	serve(r, "GET", "/product-types?includeInactive=true", nil)
+	assert.Equal(t, []bool{false, true}, got)
+
+	w = serve(r, "GET", "/product-types?includeInactive=maybe", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_include_inactive"`)
+}
+
+func TestAddProductType(t *testing.T) {
+	var got model.ProductTypeEntry
+	mock := &mockProductTypeStore{
+		addFunc: func(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+			got = pt
+			pt.Active = true
+			return &pt, nil
+		},
+	}
+
+	w := serve(setupProductTypeRouter(mock), "POST", "/product-types", map[string]any{
+		"code":           " косметика ",
+		"names":          map[string]string{"ru": " Косметика ", "en-US": "Cosmetics"},
+		"fragile":        true,
+		"maxWeightGrams": 2000,
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, model.ProductType("косметика"), got.Code, "code is trimmed")
+	assert.Equal(t, map[string]string{"ru": "Косметика", "en-US": "Cosmetics"}, got.Names)
+	assert.True(t, got.Fragile)
+	require.NotNil(t, got.MaxWeightGrams)
+	assert.Equal(t, 2000, *got.MaxWeightGrams)
+}
+
+func TestAddProductType_Invalid(t *testing.T) {
+	mock := &mockProductTypeStore{
+		addFunc: func(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+			return nil, store.ErrProductTypeAlreadyExists
+		},
+	}
+	r := setupProductTypeRouter(mock)
+	names := map[string]string{"ru": "Обувь"}
+
+	tests := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"missing code", map[string]any{"names": names}, http.StatusBadRequest, "invalid_request"},
+		{"blank code", map[string]any{"code": "  ", "names": names}, http.StatusBadRequest, "invalid_product_type_code"},
+		{"missing names", map[string]any{"code": "обувь"}, http.StatusBadRequest, "invalid_request"},
+		{"empty names", map[string]any{"code": "обувь", "names": map[string]string{}}, http.StatusBadRequest, "invalid_product_type_names"},
+		{"bad locale", map[string]any{"code": "обувь", "names": map[string]string{"Russian": "Обувь"}}, http.StatusBadRequest, "invalid_product_type_names"},
+		{"blank name", map[string]any{"code": "обувь", "names": map[string]string{"ru": " "}}, http.StatusBadRequest, "invalid_product_type_names"},
+		{"zero weight", map[string]any{"code": "обувь", "names": names, "maxWeightGrams": 0}, http.StatusBadRequest, "invalid_request"},
+		{"taken code", map[string]any{"code": "обувь", "names": names}, http.StatusConflict, "product_type_already_exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, "POST", "/product-types", tt.body)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestUpdateProductType(t *testing.T) {
+	mock := &mockProductTypeStore{
+		updateFunc: func(ctx context.Context, pt model.ProductTypeEntry) (*model.ProductTypeEntry, error) {
+			if pt.Code != model.Shoes {
+				return nil, store.ErrProductTypeNotFound
+			}
+			pt.Active = true
+			return &pt, nil
+		},
+	}
+	r := setupProductTypeRouter(mock)
+	body := map[string]any{"names": map[string]string{"ru": "Обувь и аксессуары"}, "maxWeightGrams": 3000}
+
+	w := serve(r, "PUT", "/product-types/обувь", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"maxWeightGrams":3000`)
+
+	w = serve(r, "PUT", "/product-types/мебель", body)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"product_type_not_found"`)
+
+	w = serve(r, "PUT", "/product-types/обувь", map[string]any{"names": map[string]string{"ru": "Обувь"}, "maxWeightGrams": -1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"maxWeightGrams","message":"must be at least 1"`)
+}
+
+func TestDeactivateProductType(t *testing.T) {
+	var got *bool
+	mock := &mockProductTypeStore{
+		activeFunc: func(ctx context.Context, code model.ProductType, active bool) (*model.ProductTypeEntry, error) {
+			got = &active
+			return &model.ProductTypeEntry{Code: code, Names: map[string]string{"ru": "Обувь"}, Active: active}, nil
+		},
+	}
+
+	w := serve(setupProductTypeRouter(mock), "POST", "/product-types/обувь/deactivate", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, got) {
+		assert.False(t, *got)
+	}
+	assert.Contains(t, w.Body.String(), `"active":false`)
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
+	"pvz_server/internal/app/apierror"
 	"pvz_server/internal/app/model"
 	"pvz_server/internal/app/store"
 	"strconv"
@@ -11,50 +11,38 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	errInvalidStartDate  = apierror.New(http.StatusBadRequest, "invalid_start_date", "invalid startDate")
+	errInvalidEndDate    = apierror.New(http.StatusBadRequest, "invalid_end_date", "invalid endDate")
+	errInvalidPagination = apierror.New(http.StatusBadRequest, "invalid_pagination", "invalid pagination")
+	errPageWithCursor    = apierror.New(http.StatusBadRequest, "invalid_pagination", "page cannot be combined with cursor")
+)
+
 type PVZInput struct {
 	City model.City `json:"city" binding:"required"`
 }
 
 func CreatePVZ(storeInst store.PVZCreator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
-
-		if !ok || role != "moderator" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
-			return
-		}
-
 		var req PVZInput
 
 		if err := c.ShouldBindBodyWithJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid reques"})
+			apierror.RespondBinding(c, err)
 			return
 		}
 
 		pvz, err := storeInst.CreatePVZ(c.Request.Context(), req.City)
-
-		switch {
-		case errors.Is(err, store.ErrCityNotAllowed):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unsupported city"})
-		case errors.Is(err, store.ErrDatabase):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to create PVZ"})
-		case err != nil:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unexpected error"})
-		default:
-			c.JSON(http.StatusCreated, pvz)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
 		}
+
+		c.JSON(http.StatusCreated, pvz)
 	}
 }
 
 func GetPVZList(storeInst store.PVZFetcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
-
-		if !ok || (role != "moderator" && role != "employee") {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
-			return
-		}
-
 		startDateStr := c.Query("startDate")
 		endDateStr := c.Query("endDate")
 
@@ -63,7 +51,7 @@ func GetPVZList(storeInst store.PVZFetcher) gin.HandlerFunc {
 		if startDateStr != "" {
 			t, err := time.Parse(time.RFC3339, startDateStr)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"message": "invalid startDate"})
+				apierror.Respond(c, errInvalidStartDate)
 				return
 			}
 			startDate = &t
@@ -73,7 +61,7 @@ func GetPVZList(storeInst store.PVZFetcher) gin.HandlerFunc {
 			t, err := time.Parse(time.RFC3339, endDateStr)
 
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"message": "invalid endDate"})
+				apierror.Respond(c, errInvalidEndDate)
 				return
 			}
 
@@ -84,16 +72,61 @@ func GetPVZList(storeInst store.PVZFetcher) gin.HandlerFunc {
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
 
 		if page < 1 || limit < 1 || limit > 30 {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid pagination"})
+			apierror.Respond(c, errInvalidPagination)
+			return
+		}
+
+		// The presence of cursor, even empty, switches to keyset pagination
+		// and the envelope response; page/limit clients keep the plain list.
+		if cursorStr, ok := c.GetQuery("cursor"); ok {
+			getPVZListByCursor(c, storeInst, startDate, endDate, cursorStr, limit)
 			return
 		}
 
 		pvzs, err := storeInst.FetchPVZList(c.Request.Context(), startDate, endDate, page, limit)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to fetch PVZ list"})
+			apierror.Respond(c, err)
 			return
 		}
 
 		c.JSON(http.StatusOK, pvzs)
 	}
 }
+
+type PVZListPage struct {
+	Items      []*model.PVZWithReceptions `json:"items"`
+	NextCursor *string                    `json:"nextCursor"`
+}
+
+func getPVZListByCursor(c *gin.Context, storeInst store.PVZFetcher, startDate, endDate *time.Time, cursorStr string, limit int) {
+	if _, ok := c.GetQuery("page"); ok {
+		apierror.Respond(c, errPageWithCursor)
+		return
+	}
+
+	var after *store.PVZCursor
+
+	if cursorStr != "" {
+		cursor, err := store.DecodePVZCursor(cursorStr)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+		after = cursor
+	}
+
+	pvzs, next, err := storeInst.FetchPVZListAfter(c.Request.Context(), startDate, endDate, after, limit)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	resp := PVZListPage{Items: pvzs}
+
+	if next != nil {
+		encoded := next.Encode()
+		resp.NextCursor = &encoded
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"pvz_server/internal/app/model"
@@ -15,13 +16,18 @@ import (
 )
 
 type mockPVZFetcher struct {
-	fetchFunc func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error)
+	fetchFunc      func(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error)
+	fetchAfterFunc func(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error)
 }
 
 func (m *mockPVZFetcher) FetchPVZList(ctx context.Context, start, end *time.Time, page, limit int) ([]*model.PVZWithReceptions, error) {
 	return m.fetchFunc(ctx, start, end, page, limit)
 }
 
+func (m *mockPVZFetcher) FetchPVZListAfter(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+	return m.fetchAfterFunc(ctx, start, end, after, limit)
+}
+
 func setupPVZGetRouter(role string, fetcher *mockPVZFetcher) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
@@ -57,18 +63,6 @@ func TestGetPVZList_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Москва")
 }
 
-func TestGetPVZList_InvalidRole(t *testing.T) {
-	mock := &mockPVZFetcher{}
-	router := setupPVZGetRouter("guest", mock)
-
-	req, _ := http.NewRequest("GET", "/pvz", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
-}
-
 func TestGetPVZList_InvalidDate(t *testing.T) {
 	mock := &mockPVZFetcher{}
 	router := setupPVZGetRouter("moderator", mock)
@@ -105,6 +99,91 @@ func TestGetPVZList_DatabaseError(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database is unavailable")
+}
+
+func TestGetPVZList_CursorFirstPage(t *testing.T) {
+	next := store.PVZCursor{
+		RegistrationDate: time.Date(2025, 4, 13, 10, 0, 0, 0, time.UTC),
+		ID:               "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11",
+	}
+
+	var gotAfter *store.PVZCursor
+	var gotLimit int
+	mock := &mockPVZFetcher{
+		fetchAfterFunc: func(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+			gotAfter, gotLimit = after, limit
+			return []*model.PVZWithReceptions{{PVZ: model.PVZ{ID: next.ID, City: "Казань"}}}, &next, nil
+		},
+	}
+
+	router := setupPVZGetRouter("employee", mock)
+	req, _ := http.NewRequest("GET", "/pvz?cursor=&limit=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, gotAfter)
+	assert.Equal(t, 1, gotLimit)
+
+	var page handlers.PVZListPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 1)
+	if assert.NotNil(t, page.NextCursor) {
+		decoded, err := store.DecodePVZCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, next.ID, decoded.ID)
+		assert.True(t, next.RegistrationDate.Equal(decoded.RegistrationDate))
+	}
+}
+
+func TestGetPVZList_CursorNextPage(t *testing.T) {
+	cursor := store.PVZCursor{
+		RegistrationDate: time.Date(2025, 4, 13, 10, 0, 0, 0, time.UTC),
+		ID:               "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11",
+	}
+
+	var gotAfter *store.PVZCursor
+	mock := &mockPVZFetcher{
+		fetchAfterFunc: func(ctx context.Context, start, end *time.Time, after *store.PVZCursor, limit int) ([]*model.PVZWithReceptions, *store.PVZCursor, error) {
+			gotAfter = after
+			return []*model.PVZWithReceptions{}, nil, nil
+		},
+	}
+
+	router := setupPVZGetRouter("moderator", mock)
+	req, _ := http.NewRequest("GET", "/pvz?cursor="+cursor.Encode(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items":[],"nextCursor":null}`, w.Body.String())
+	if assert.NotNil(t, gotAfter) {
+		assert.Equal(t, cursor.ID, gotAfter.ID)
+	}
+}
+
+func TestGetPVZList_InvalidCursor(t *testing.T) {
+	mock := &mockPVZFetcher{}
+	router := setupPVZGetRouter("employee", mock)
+
+	req, _ := http.NewRequest("GET", "/pvz?cursor=garbage", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid cursor")
+}
+
+func TestGetPVZList_CursorWithPage(t *testing.T) {
+	mock := &mockPVZFetcher{}
+	router := setupPVZGetRouter("employee", mock)
+
+	req, _ := http.NewRequest("GET", "/pvz?cursor=&page=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "failed to fetch PVZ list")
+	assert.Contains(t, w.Body.String(), "page cannot be combined with cursor")
 }
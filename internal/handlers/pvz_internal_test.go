@@ -65,19 +65,6 @@ func TestCreatePVZ_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Москва")
 }
 
-func TestCreatePVZ_InvalidRole(t *testing.T) {
-	mock := &mockStore{}
-	router := setupRouterWithRole("employee", mock)
-
-	req, _ := http.NewRequest("POST", "/pvz", nil)
-	w := httptest.NewRecorder()
-
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
-}
-
 func TestCreatePVZ_InvalidBody(t *testing.T) {
 	mock := &mockStore{}
 	router := setupRouterWithRole("moderator", mock)
@@ -114,7 +101,7 @@ func TestCreatePVZ_UnsupportedCity(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	assert.Contains(t, w.Body.String(), "unsupported city")
 }
 
@@ -135,8 +122,8 @@ func TestCreatePVZ_DatabaseError(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "failed to create")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database is unavailable")
 }
 
 func TestCreatePVZ_UnexpectedError(t *testing.T) {
@@ -156,6 +143,6 @@ func TestCreatePVZ_UnexpectedError(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "unexpected error")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "internal server error")
 }
@@ -1,75 +1,65 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
+	"pvz_server/internal/app/apierror"
 	"pvz_server/internal/app/store"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type ReceprionInput struct {
 	PVZID string `json:"pvzId" binding:"required"`
 }
 
-func CreateReception(storeInst store.ReceptionCreator) gin.HandlerFunc {
+func CreateReception(storeInst store.ReceptionCreator, assignments store.AssignmentChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
+		var req ReceprionInput
 
-		if !ok || role != "employee" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
 			return
 		}
 
-		var req ReceprionInput
+		if uuid.Validate(req.PVZID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
+			return
+		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request"})
+		if !ensureAssigned(c, assignments, req.PVZID) {
 			return
 		}
 
 		reception, err := storeInst.CreateReception(c.Request.Context(), req.PVZID)
-
-		switch {
-		case err == store.ErrReceptionAlreadyExists:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "previous reception is not closed"})
-		case err == store.ErrDatabase:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to create reception"})
-		case err != nil:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unexpected error"})
-		default:
-			c.JSON(http.StatusCreated, reception)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
 		}
+
+		c.JSON(http.StatusCreated, reception)
 	}
 }
 
-func CloseLastReception(storeInst store.ReceptionCloser) gin.HandlerFunc {
+func CloseLastReception(storeInst store.ReceptionCloser, assignments store.AssignmentChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, ok := c.Get("role")
+		pvzID := c.Param("pvzId")
 
-		if !ok || role != "employee" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "access denied"})
+		if uuid.Validate(pvzID) != nil {
+			apierror.Respond(c, apierror.ErrInvalidPVZID)
 			return
 		}
 
-		pvzID := c.Param("pvzId")
-
-		if pvzID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "pvzId is required"})
+		if !ensureAssigned(c, assignments, pvzID) {
 			return
 		}
 
 		reception, err := storeInst.CloseLastReception(c.Request.Context(), pvzID)
-
-		switch {
-		case errors.Is(err, store.ErrNoActiveReception):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "no active reception to close"})
-		case errors.Is(err, store.ErrDatabase):
-			c.JSON(http.StatusBadRequest, gin.H{"message": "failed to close reception"})
-		case err != nil:
-			c.JSON(http.StatusBadRequest, gin.H{"message": "unexpected error"})
-		default:
-			c.JSON(http.StatusOK, reception)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
 		}
+
+		c.JSON(http.StatusOK, reception)
 	}
 }
@@ -17,6 +17,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+const testPVZID = "7f1c2a52-34a4-4c8e-9d0c-0c3f3b8a6f11"
+
 type mockReceptionStore struct {
 	createFunc func(ctx context.Context, pvzID string) (*model.Reception, error)
 	closeFunc  func(ctx context.Context, pvzID string) (*model.Reception, error)
@@ -45,7 +47,7 @@ func setupReceptionRouterWithRole(role string, store receptionStoreInterface) *g
 		c.Next()
 	})
 
-	r.POST("/receptions", handlers.CreateReception(store.(*mockReceptionStore)))
+	r.POST("/receptions", handlers.CreateReception(store.(*mockReceptionStore), assignedTo(testPVZID)))
 	return r
 }
 
@@ -58,7 +60,7 @@ func setupCloseRouterWithRole(role string, store *mockReceptionStore) *gin.Engin
 		c.Next()
 	})
 
-	r.POST("/pvz/:pvzId/close_last_reception", handlers.CloseLastReception(store))
+	r.POST("/pvz/:pvzId/close_last_reception", handlers.CloseLastReception(store, assignedTo(testPVZID)))
 	return r
 }
 
@@ -75,7 +77,7 @@ func TestCreateReception_Success(t *testing.T) {
 	}
 	router := setupReceptionRouterWithRole("employee", mock)
 
-	body := map[string]string{"pvzId": "pvz1"}
+	body := map[string]string{"pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
@@ -85,24 +87,7 @@ func TestCreateReception_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
-	assert.Contains(t, w.Body.String(), `"pvzId":"pvz1"`)
-}
-
-func TestCreateReception_InvalidRole(t *testing.T) {
-	mock := &mockReceptionStore{}
-	router := setupReceptionRouterWithRole("moderator", mock)
-
-	body := map[string]string{"pvzId": "pvz1"}
-	jsonBody, _ := json.Marshal(body)
-
-	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
+	assert.Contains(t, w.Body.String(), `"pvzId":"`+testPVZID+`"`)
 }
 
 func TestCreateReception_InvalidBody(t *testing.T) {
@@ -127,7 +112,7 @@ func TestCreateReception_AlreadyExists(t *testing.T) {
 	}
 	router := setupReceptionRouterWithRole("employee", mock)
 
-	body := map[string]string{"pvzId": "pvz1"}
+	body := map[string]string{"pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
@@ -136,7 +121,7 @@ func TestCreateReception_AlreadyExists(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
 	assert.Contains(t, w.Body.String(), "previous reception is not closed")
 }
 
@@ -148,7 +133,7 @@ func TestCreateReception_DatabaseError(t *testing.T) {
 	}
 	router := setupReceptionRouterWithRole("employee", mock)
 
-	body := map[string]string{"pvzId": "pvz1"}
+	body := map[string]string{"pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
@@ -157,8 +142,8 @@ func TestCreateReception_DatabaseError(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "failed to create reception")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database is unavailable")
 }
 
 func TestCreateReception_UnexpectedError(t *testing.T) {
@@ -169,7 +154,7 @@ func TestCreateReception_UnexpectedError(t *testing.T) {
 	}
 	router := setupReceptionRouterWithRole("employee", mock)
 
-	body := map[string]string{"pvzId": "pvz1"}
+	body := map[string]string{"pvzId": testPVZID}
 	jsonBody, _ := json.Marshal(body)
 
 	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
@@ -178,8 +163,8 @@ func TestCreateReception_UnexpectedError(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "unexpected error")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "internal server error")
 }
 
 func TestCloseReception_Success(t *testing.T) {
@@ -196,7 +181,7 @@ func TestCloseReception_Success(t *testing.T) {
 
 	router := setupCloseRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/close_last_reception", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/close_last_reception", nil)
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
@@ -205,19 +190,6 @@ func TestCloseReception_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), `"status":"close"`)
 }
 
-func TestCloseReception_InvalidRole(t *testing.T) {
-	mock := &mockReceptionStore{}
-	router := setupCloseRouterWithRole("moderator", mock)
-
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/close_last_reception", nil)
-	w := httptest.NewRecorder()
-
-	router.ServeHTTP(w, req)
-
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "access denied")
-}
-
 func TestCloseReception_NoActiveReception(t *testing.T) {
 	mock := &mockReceptionStore{
 		closeFunc: func(ctx context.Context, pvzID string) (*model.Reception, error) {
@@ -227,12 +199,12 @@ func TestCloseReception_NoActiveReception(t *testing.T) {
 
 	router := setupCloseRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/close_last_reception", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/close_last_reception", nil)
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
 	assert.Contains(t, w.Body.String(), "no active reception")
 }
 
@@ -245,13 +217,13 @@ func TestCloseReception_DatabaseError(t *testing.T) {
 
 	router := setupCloseRouterWithRole("employee", mock)
 
-	req, _ := http.NewRequest("POST", "/pvz/pvz1/close_last_reception", nil)
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/close_last_reception", nil)
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "failed to close reception")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database is unavailable")
 }
 
 func TestCloseReception_UnexpectedError(t *testing.T) {
@@ -263,11 +235,79 @@ func TestCloseReception_UnexpectedError(t *testing.T) {
 
 	router := setupCloseRouterWithRole("employee", mock)
 
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/close_last_reception", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "internal server error")
+}
+
+func TestCreateReception_InvalidPVZID(t *testing.T) {
+	mock := &mockReceptionStore{}
+	router := setupReceptionRouterWithRole("employee", mock)
+
+	body := map[string]string{"pvzId": "pvz1"}
+	jsonBody, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid pvz ID")
+}
+
+func TestCreateReception_PVZNotFound(t *testing.T) {
+	mock := &mockReceptionStore{
+		createFunc: func(ctx context.Context, pvzID string) (*model.Reception, error) {
+			return nil, store.ErrPVZNotFound
+		},
+	}
+	router := setupReceptionRouterWithRole("employee", mock)
+
+	body := map[string]string{"pvzId": testPVZID}
+	jsonBody, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", "/receptions", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "pvz not found")
+}
+
+func TestCloseReception_InvalidPVZID(t *testing.T) {
+	mock := &mockReceptionStore{}
+	router := setupCloseRouterWithRole("employee", mock)
+
 	req, _ := http.NewRequest("POST", "/pvz/pvz1/close_last_reception", nil)
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "unexpected error")
+	assert.Contains(t, w.Body.String(), "invalid pvz ID")
+}
+
+func TestCloseReception_PVZNotFound(t *testing.T) {
+	mock := &mockReceptionStore{
+		closeFunc: func(ctx context.Context, pvzID string) (*model.Reception, error) {
+			return nil, store.ErrPVZNotFound
+		},
+	}
+	router := setupCloseRouterWithRole("employee", mock)
+
+	req, _ := http.NewRequest("POST", "/pvz/"+testPVZID+"/close_last_reception", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "pvz not found")
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"pvz_server/internal/app/apierror"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidWebhookURL     = apierror.New(http.StatusBadRequest, "invalid_webhook_url", "webhook url must be an absolute http or https url")
+	errInvalidWebhookID      = apierror.New(http.StatusBadRequest, "invalid_webhook_id", "invalid webhook ID")
+	errInvalidDeliveryID     = apierror.New(http.StatusBadRequest, "invalid_delivery_id", "invalid delivery ID")
+	errInvalidDeliveryStatus = apierror.New(http.StatusBadRequest, "invalid_delivery_status", "invalid delivery status")
+)
+
+type WebhookInput struct {
+	URL        string            `json:"url" binding:"required"`
+	Secret     string            `json:"secret" binding:"required,min=16"`
+	EventTypes []model.EventType `json:"eventTypes" binding:"required,min=1"`
+}
+
+func CreateWebhook(storeInst store.WebhookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WebhookInput
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.RespondBinding(c, err)
+			return
+		}
+
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			apierror.Respond(c, errInvalidWebhookURL)
+			return
+		}
+
+		webhook, err := storeInst.CreateWebhook(c.Request.Context(), req.URL, req.Secret, req.EventTypes)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+func ListWebhooks(storeInst store.WebhookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := storeInst.ListWebhooks(c.Request.Context())
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func DeleteWebhook(storeInst store.WebhookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("webhookId")
+
+		if uuid.Validate(webhookID) != nil {
+			apierror.Respond(c, errInvalidWebhookID)
+			return
+		}
+
+		if err := storeInst.DeleteWebhook(c.Request.Context(), webhookID); err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListWebhookDeliveries lists deliveries of a webhook newest first,
+// optionally only those with the given status.
+func ListWebhookDeliveries(storeInst store.WebhookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("webhookId")
+
+		if uuid.Validate(webhookID) != nil {
+			apierror.Respond(c, errInvalidWebhookID)
+			return
+		}
+
+		filter := store.DeliveryFilter{Status: model.DeliveryStatus(c.Query("status"))}
+
+		if filter.Status != "" && !model.AllowedDeliveryStatuses[filter.Status] {
+			apierror.Respond(c, errInvalidDeliveryStatus)
+			return
+		}
+
+		filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+		filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+		if filter.Page < 1 || filter.Limit < 1 || filter.Limit > 100 {
+			apierror.Respond(c, errInvalidPagination)
+			return
+		}
+
+		deliveries, err := storeInst.ListWebhookDeliveries(c.Request.Context(), webhookID, filter)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// ReplayWebhookDelivery queues a dead delivery again with a fresh attempt
+// budget.
+func ReplayWebhookDelivery(storeInst store.WebhookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("webhookId")
+		deliveryID := c.Param("deliveryId")
+
+		if uuid.Validate(webhookID) != nil {
+			apierror.Respond(c, errInvalidWebhookID)
+			return
+		}
+
+		if uuid.Validate(deliveryID) != nil {
+			apierror.Respond(c, errInvalidDeliveryID)
+			return
+		}
+
+		delivery, err := storeInst.ReplayWebhookDelivery(c.Request.Context(), webhookID, deliveryID)
+		if err != nil {
+			apierror.Respond(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
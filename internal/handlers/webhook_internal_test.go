@@ -0,0 +1,206 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pvz_server/internal/app/model"
+	"pvz_server/internal/app/store"
+	"pvz_server/internal/handlers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testWebhookID  = "6f1c2b9e-0a4d-4c6e-9a57-3b8f2d1e7c40"
+	testDeliveryID = "9b7e5d3c-1f2a-4e8b-b6c4-0d9a8e7f6c51"
+)
+
+type mockWebhookStore struct {
+	createFunc  func(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error)
+	deleteFunc  func(ctx context.Context, id string) error
+	listDelFunc func(ctx context.Context, webhookID string, filter store.DeliveryFilter) ([]model.WebhookDelivery, error)
+	replayFunc  func(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error)
+}
+
+func (m *mockWebhookStore) CreateWebhook(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error) {
+	return m.createFunc(ctx, url, secret, eventTypes)
+}
+
+func (m *mockWebhookStore) ListWebhooks(ctx context.Context) ([]model.WebhookSubscription, error) {
+	return []model.WebhookSubscription{}, nil
+}
+
+func (m *mockWebhookStore) DeleteWebhook(ctx context.Context, id string) error {
+	return m.deleteFunc(ctx, id)
+}
+
+func (m *mockWebhookStore) ListWebhookDeliveries(ctx context.Context, webhookID string, filter store.DeliveryFilter) ([]model.WebhookDelivery, error) {
+	return m.listDelFunc(ctx, webhookID, filter)
+}
+
+func (m *mockWebhookStore) ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	return m.replayFunc(ctx, webhookID, deliveryID)
+}
+
+func setupWebhookRouter(mock *mockWebhookStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.POST("/webhooks", handlers.CreateWebhook(mock))
+	r.GET("/webhooks", handlers.ListWebhooks(mock))
+	r.DELETE("/webhooks/:webhookId", handlers.DeleteWebhook(mock))
+	r.GET("/webhooks/:webhookId/deliveries", handlers.ListWebhookDeliveries(mock))
+	r.POST("/webhooks/:webhookId/deliveries/:deliveryId/replay", handlers.ReplayWebhookDelivery(mock))
+	return r
+}
+
+func serve(r *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateWebhook_Success(t *testing.T) {
+	mock := &mockWebhookStore{
+		createFunc: func(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error) {
+			return &model.WebhookSubscription{ID: testWebhookID, URL: url, Secret: secret, EventTypes: eventTypes, CreatedAt: time.Now()}, nil
+		},
+	}
+
+	w := serve(setupWebhookRouter(mock), "POST", "/webhooks", map[string]any{
+		"url":        "https://partner.example.com/pvz",
+		"secret":     "0123456789abcdef",
+		"eventTypes": []string{"reception.closed"},
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"eventTypes":["reception.closed"]`)
+	assert.NotContains(t, w.Body.String(), "0123456789abcdef", "the secret must not be echoed")
+}
+
+func TestCreateWebhook_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body map[string]any
+		code string
+	}{
+		{"relative url", map[string]any{"url": "/hook", "secret": "0123456789abcdef", "eventTypes": []string{"product.added"}}, "invalid_webhook_url"},
+		{"ftp url", map[string]any{"url": "ftp://example.com", "secret": "0123456789abcdef", "eventTypes": []string{"product.added"}}, "invalid_webhook_url"},
+		{"short secret", map[string]any{"url": "http://example.com", "secret": "short", "eventTypes": []string{"product.added"}}, "invalid_request"},
+		{"no event types", map[string]any{"url": "http://example.com", "secret": "0123456789abcdef", "eventTypes": []string{}}, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(setupWebhookRouter(&mockWebhookStore{}), "POST", "/webhooks", tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestCreateWebhook_EventTypeNotAllowed(t *testing.T) {
+	mock := &mockWebhookStore{
+		createFunc: func(ctx context.Context, url, secret string, eventTypes []model.EventType) (*model.WebhookSubscription, error) {
+			return nil, store.ErrEventTypeNotAllowed
+		},
+	}
+
+	w := serve(setupWebhookRouter(mock), "POST", "/webhooks", map[string]any{
+		"url":        "http://example.com",
+		"secret":     "0123456789abcdef",
+		"eventTypes": []string{"pvz.deleted"},
+	})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestCreateWebhook_EmptyEventTypesDetails(t *testing.T) {
+	w := serve(setupWebhookRouter(&mockWebhookStore{}), "POST", "/webhooks", map[string]any{
+		"url":        "http://example.com",
+		"secret":     "0123456789abcdef",
+		"eventTypes": []string{},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"field":"eventTypes","message":"must contain at least 1 items"}`)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	mock := &mockWebhookStore{
+		deleteFunc: func(ctx context.Context, id string) error {
+			if id != testWebhookID {
+				return store.ErrWebhookNotFound
+			}
+			return nil
+		},
+	}
+	r := setupWebhookRouter(mock)
+
+	assert.Equal(t, http.StatusNoContent, serve(r, "DELETE", "/webhooks/"+testWebhookID, nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, "DELETE", "/webhooks/"+testDeliveryID, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, "DELETE", "/webhooks/hook", nil).Code)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	var got store.DeliveryFilter
+	mock := &mockWebhookStore{
+		listDelFunc: func(ctx context.Context, webhookID string, filter store.DeliveryFilter) ([]model.WebhookDelivery, error) {
+			got = filter
+			return []model.WebhookDelivery{{ID: testDeliveryID, Status: model.DeliveryDead}}, nil
+		},
+	}
+	r := setupWebhookRouter(mock)
+
+	w := serve(r, "GET", "/webhooks/"+testWebhookID+"/deliveries?status=dead&limit=5", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"dead"`)
+	assert.Equal(t, store.DeliveryFilter{Status: model.DeliveryDead, Page: 1, Limit: 5}, got)
+
+	w = serve(r, "GET", "/webhooks/"+testWebhookID+"/deliveries?status=failed", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_delivery_status")
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	mock := &mockWebhookStore{
+		replayFunc: func(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+			return &model.WebhookDelivery{ID: deliveryID, WebhookID: webhookID, Status: model.DeliveryPending}, nil
+		},
+	}
+
+	w := serve(setupWebhookRouter(mock), "POST", "/webhooks/"+testWebhookID+"/deliveries/"+testDeliveryID+"/replay", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+}
+
+func TestReplayWebhookDelivery_NotDead(t *testing.T) {
+	mock := &mockWebhookStore{
+		replayFunc: func(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+			return nil, store.ErrDeliveryNotDead
+		},
+	}
+	r := setupWebhookRouter(mock)
+
+	w := serve(r, "POST", "/webhooks/"+testWebhookID+"/deliveries/"+testDeliveryID+"/replay", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serve(r, "POST", "/webhooks/"+testWebhookID+"/deliveries/d-1/replay", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,20 +1,105 @@
 package utils
 
 import (
-	"os"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"pvz_server/internal/app/jwtkeys"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessToken holds the claims of a short-lived access token.
+type AccessToken struct {
+	UserID    string
+	Role      string
+	JTI       string
+	ExpiresAt time.Time
+}
 
-func GenerateJWT(role string) (string, error) {
+func NewAccessToken(userID, role string) AccessToken {
+	return AccessToken{
+		UserID:    userID,
+		Role:      role,
+		JTI:       uuid.NewString(),
+		ExpiresAt: time.Now().Add(AccessTokenTTL),
+	}
+}
+
+func GenerateJWT(keys *jwtkeys.Manager, t AccessToken) (string, error) {
 	claims := jwt.MapClaims{
-		"role": role,
-		"exp":  jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+		"user_id": t.UserID,
+		"role":    t.Role,
+		"jti":     t.JTI,
+		"exp":     jwt.NewNumericDate(t.ExpiresAt),
+	}
+
+	return keys.Sign(claims)
+}
+
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// ParseAccessToken verifies the signature and expiry of an access token and
+// returns its claims. Tokens without a role, jti or expiry are rejected.
+func ParseAccessToken(keys *jwtkeys.Manager, tokenString string) (AccessToken, error) {
+	token, err := keys.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return AccessToken{}, ErrInvalidAccessToken
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+
+	return AccessToken{
+		UserID:    userID,
+		Role:      role,
+		JTI:       jti,
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// GenerateRefreshToken returns an opaque refresh token for the client and
+// the hash under which it is stored.
+func GenerateRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
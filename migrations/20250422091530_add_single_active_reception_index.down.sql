@@ -0,0 +1 @@
+DROP INDEX IF EXISTS uniq_reception_pvz_in_progress;
\ No newline at end of file
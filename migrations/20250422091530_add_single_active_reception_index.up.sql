@@ -0,0 +1,24 @@
+-- Races in CreateReception could leave several in_progress receptions for a
+-- PVZ. Which of them is real is not for a migration to decide, so it stops
+-- and lists them; close all but one per PVZ and run it again.
+DO $$
+DECLARE
+    conflicts TEXT;
+BEGIN
+    SELECT string_agg(format('pvz %s: %s', pvz_id, receptions), E'\n' ORDER BY pvz_id)
+    INTO conflicts
+    FROM (
+        SELECT pvz_id, string_agg(format('%s (%s)', id, date_time), ', ' ORDER BY date_time, id) AS receptions
+        FROM reception
+        WHERE status = 'in_progress'
+        GROUP BY pvz_id
+        HAVING COUNT(*) > 1
+    ) duplicates;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'several in_progress receptions per PVZ, close all but one before migrating:%', E'\n' || conflicts;
+    END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_reception_pvz_in_progress ON reception(pvz_id) WHERE status = 'in_progress';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS employee_pvz;
\ No newline at end of file
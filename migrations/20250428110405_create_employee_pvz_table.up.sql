@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS employee_pvz (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, pvz_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_employee_pvz_pvz_id ON employee_pvz(pvz_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_outbox_pvz_id ON outbox(pvz_id, seq);
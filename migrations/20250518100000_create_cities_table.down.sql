@@ -0,0 +1,8 @@
+ALTER TABLE pvz DROP CONSTRAINT IF EXISTS pvz_city_fkey;
+
+-- Fails if PVZs were created in cities added after the up migration.
+ALTER TABLE pvz ADD CONSTRAINT pvz_city_check
+    CHECK (city IN ('Москва', 'Санкт-Петербург', 'Казань'));
+
+DROP TABLE IF EXISTS city_aliases;
+DROP TABLE IF EXISTS cities;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS cities (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Former names of a city, still accepted when creating a PVZ.
+CREATE TABLE IF NOT EXISTS city_aliases (
+    alias TEXT PRIMARY KEY,
+    city_id UUID NOT NULL REFERENCES cities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_city_aliases_city_id ON city_aliases(city_id);
+
+INSERT INTO cities (id, name)
+VALUES
+    (gen_random_uuid(), 'Москва'),
+    (gen_random_uuid(), 'Санкт-Петербург'),
+    (gen_random_uuid(), 'Казань')
+ON CONFLICT (name) DO NOTHING;
+
+-- Keep every city already used by a PVZ, so the foreign key below holds
+-- for existing rows.
+INSERT INTO cities (id, name)
+SELECT gen_random_uuid(), city FROM pvz GROUP BY city
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE pvz DROP CONSTRAINT IF EXISTS pvz_city_check;
+
+-- Renaming a city renames it in every PVZ.
+ALTER TABLE pvz ADD CONSTRAINT pvz_city_fkey
+    FOREIGN KEY (city) REFERENCES cities(name) ON UPDATE CASCADE;
@@ -0,0 +1,7 @@
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_type_fkey;
+
+-- Fails if products were added with types created after the up migration.
+ALTER TABLE product ADD CONSTRAINT product_type_check
+    CHECK (type IN ('электроника', 'одежда', 'обувь'));
+
+DROP TABLE IF EXISTS product_types;
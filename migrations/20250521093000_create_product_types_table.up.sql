@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS product_types (
+    code TEXT PRIMARY KEY,
+    -- Display names by locale, e.g. {"ru": "Обувь", "en": "Shoes"}.
+    names JSONB NOT NULL,
+    fragile BOOLEAN NOT NULL DEFAULT FALSE,
+    max_weight_grams INTEGER CHECK (max_weight_grams > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO product_types (code, names, fragile)
+VALUES
+    ('электроника', '{"ru": "Электроника", "en": "Electronics"}', TRUE),
+    ('одежда', '{"ru": "Одежда", "en": "Clothing"}', FALSE),
+    ('обувь', '{"ru": "Обувь", "en": "Shoes"}', FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Keep every type already used by a product, so the foreign key below
+-- holds for existing rows.
+INSERT INTO product_types (code, names)
+SELECT type, jsonb_build_object('ru', type) FROM product GROUP BY type
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_type_check;
+
+ALTER TABLE product ADD CONSTRAINT product_type_fkey
+    FOREIGN KEY (type) REFERENCES product_types(code);
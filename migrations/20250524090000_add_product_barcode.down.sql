@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_product_barcode;
+DROP INDEX IF EXISTS product_reception_barcode_key;
+
+ALTER TABLE product
+    DROP COLUMN IF EXISTS order_id,
+    DROP COLUMN IF EXISTS barcode;
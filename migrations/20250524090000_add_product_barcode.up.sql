@@ -0,0 +1,9 @@
+ALTER TABLE product
+    ADD COLUMN IF NOT EXISTS barcode TEXT,
+    ADD COLUMN IF NOT EXISTS order_id TEXT;
+
+-- A barcode identifies one item of a reception; products without one are
+-- not constrained.
+CREATE UNIQUE INDEX IF NOT EXISTS product_reception_barcode_key ON product(reception_id, barcode);
+
+CREATE INDEX IF NOT EXISTS idx_product_barcode ON product(barcode) WHERE barcode IS NOT NULL;
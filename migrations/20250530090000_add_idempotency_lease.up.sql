@@ -0,0 +1,4 @@
+-- A reservation whose request never finished (status still NULL) can be
+-- reclaimed once locked_until has passed. Reservations made before leases
+-- existed can be reclaimed right away.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_unsequenced;
+DROP INDEX IF EXISTS idx_outbox_pvz_stream_seq;
+DROP INDEX IF EXISTS outbox_stream_seq_key;
+ALTER TABLE outbox DROP COLUMN IF EXISTS stream_seq;
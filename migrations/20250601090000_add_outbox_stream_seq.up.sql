@@ -0,0 +1,13 @@
+-- stream_seq orders events for PVZ streams by commit instead of by insert.
+-- It is assigned only to committed events, one run at a time, so a reader
+-- that has seen stream_seq N has already seen every lower number. Existing
+-- events keep their seq, so clients resume with the IDs they hold.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS stream_seq BIGINT;
+
+UPDATE outbox SET stream_seq = seq WHERE stream_seq IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS outbox_stream_seq_key ON outbox(stream_seq);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pvz_stream_seq ON outbox(pvz_id, stream_seq);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unsequenced ON outbox(seq) WHERE stream_seq IS NULL;